@@ -0,0 +1,101 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// BuildOption is a functional option interface that modify di.Build() behaviour.
+type BuildOption interface {
+	applyBuild(params *BuildParams)
+}
+
+// BuildParams is a di.Build() parameters.
+type BuildParams struct {
+	// Fields are literal values of struct fields by field name
+	Fields map[string]Value
+}
+
+func (p BuildParams) applyBuild(params *BuildParams) {
+	*params = p
+}
+
+// Set modifies di.Build() behavior. The field with name is set to value instead of injecting it
+// from the container.
+func Set(name string, value Value) BuildOption {
+	return buildOption(func(params *BuildParams) {
+		if params.Fields == nil {
+			params.Fields = map[string]Value{}
+		}
+		params.Fields[name] = value
+	})
+}
+
+type buildOption func(params *BuildParams)
+
+func (o buildOption) applyBuild(params *BuildParams) {
+	o(params)
+}
+
+// Build creates struct T, or pointer to struct, with embedded di.Inject. Fields are injected
+// from the container, fields set by di.Set() options are set to the values instead. T is not
+// provided into the container. It is useful for partially configured objects in tests:
+//
+//	config, err := di.Build[*Config](container, di.Set("Port", 8080))
+//	if err != nil {
+//		// handle error
+//	}
+func Build[T any](c *Container, options ...BuildOption) (T, error) {
+	var result T
+	params := BuildParams{}
+	for _, opt := range options {
+		opt.applyBuild(&params)
+	}
+	rt := reflect.TypeOf(&result).Elem()
+	if !canInject(rt) {
+		return result, errWithStack(fmt.Errorf("struct with di.Inject can be built, got %s", rt))
+	}
+	if err := checkUnexportedFields(rt); err != nil {
+		return result, errWithStack(err)
+	}
+	rv := reflect.ValueOf(&result).Elem()
+	if rt.Kind() == reflect.Ptr {
+		rv.Set(reflect.New(rt.Elem()))
+		rv = rv.Elem()
+	}
+	for name, value := range params.Fields {
+		f := rv.FieldByName(name)
+		if !f.IsValid() || !f.CanSet() {
+			return result, errWithStack(fmt.Errorf("%s has no exported field %s", rv.Type(), name))
+		}
+		v := reflect.ValueOf(value)
+		if value == nil {
+			v = reflect.Zero(f.Type())
+		}
+		if !v.Type().AssignableTo(f.Type()) {
+			return result, errWithStack(fmt.Errorf("%s.%s: %s not assignable to %s", rv.Type(), name, v.Type(), f.Type()))
+		}
+		f.Set(v)
+	}
+	for index, field := range parsePopulateFields(rt) {
+		if _, ok := params.Fields[rv.Type().Field(index).Name]; ok {
+			continue
+		}
+		n, err := c.schema.find(field.rt, field.tags)
+		if err != nil && field.optional {
+			continue
+		}
+		if err != nil {
+			return result, errWithStack(err)
+		}
+		if err := c.schema.prepare(n); err != nil {
+			return result, errWithStack(err)
+		}
+		value, err := n.Value(c.schema)
+		if err != nil {
+			return result, errWithStack(fmt.Errorf("%s: %w", n, err))
+		}
+		rv.Field(index).Set(value)
+	}
+	return result, nil
+}
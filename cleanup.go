@@ -0,0 +1,175 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// destructor is a cleanup of resolved instance.
+type destructor struct {
+	// node which instance will be cleaned up
+	node *node
+	fn   func(r CleanupResolver) error
+}
+
+// run runs cleanup function.
+func (d *destructor) run(r CleanupResolver) error {
+	if err := d.fn(r); err != nil {
+		return fmt.Errorf("%s cleanup: %w", d.node, err)
+	}
+	return nil
+}
+
+// runWithin runs cleanup function in goroutine and waits it at most timeout. The cleanup that
+// exceeds timeout is skipped, it is not stopped and may complete in background.
+func (d *destructor) runWithin(r CleanupResolver, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- d.run(r)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%s cleanup %w: exceeded %s", d.node, ErrCleanupSkipped, timeout)
+	}
+}
+
+// CleanupResolver resolves instances in cleanup functions. Constructor can return cleanup
+// func(di.CleanupResolver) or func(di.CleanupResolver) error to access other instances on
+// shutdown, like a logger. Only instances that were built and are not cleaned up yet can be
+// resolved, the resolver doesn't build new instances.
+//
+//	func NewServer() (*http.Server, func(r di.CleanupResolver) error) {
+//		server := &http.Server{}
+//		return server, func(r di.CleanupResolver) error {
+//			var logger *log.Logger
+//			if err := r.Resolve(&logger); err != nil {
+//				return err
+//			}
+//			logger.Println("server stopped")
+//			return server.Close()
+//		}
+//	}
+type CleanupResolver interface {
+	// Resolve resolves built instance that is not cleaned up yet.
+	Resolve(ptr Pointer, options ...ResolveOption) error
+}
+
+var cleanupResolverType = reflect.TypeOf(new(CleanupResolver)).Elem()
+
+// cleanupResolver is a container resolver that is passed to cleanups.
+type cleanupResolver struct {
+	container *Container
+	// mu guards cleaned, skipped cleanups run concurrently
+	mu      sync.Mutex
+	cleaned map[*reflect.Value]bool
+}
+
+// clean marks instance of node as cleaned up.
+func (r *cleanupResolver) clean(n *node) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cleaned[n.rv] = true
+}
+
+// Resolve resolves built instance that is not cleaned up yet.
+func (r *cleanupResolver) Resolve(ptr Pointer, options ...ResolveOption) error {
+	n, err := r.container.find(ptr, options...)
+	if err != nil {
+		return errWithStack(err)
+	}
+	members := []*node{n}
+	if group, ok := n.compiler.(*groupCompiler); ok {
+		members = group.matched
+	}
+	for _, member := range members {
+		if err := r.check(member); err != nil {
+			return errWithStack(err)
+		}
+	}
+	if err := r.container.resolve(ptr, options...); err != nil {
+		return errWithStack(err)
+	}
+	return nil
+}
+
+// check checks that node instance is built and is not cleaned up.
+func (r *cleanupResolver) check(n *node) error {
+	if !r.provided(n) {
+		return fmt.Errorf("%s can't be resolved on cleanup, only provided types can", n)
+	}
+	r.mu.Lock()
+	cleaned := r.cleaned[n.rv]
+	r.mu.Unlock()
+	if cleaned {
+		return fmt.Errorf("%s is already cleaned up", n)
+	}
+	rv, _, mu, err := n.storage(r.container.schema)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	built := n.lifetime != LifetimeTransient && rv.IsValid()
+	mu.Unlock()
+	if !built {
+		return fmt.Errorf("%s is not built, cleanup can't build instances", n)
+	}
+	return nil
+}
+
+// provided checks that node is provided into container or its ancestors.
+func (r *cleanupResolver) provided(n *node) bool {
+	nodes, _ := r.container.schema.list(n.rt)
+	return contains(nodes, n)
+}
+
+// CleanupOption is a functional option interface that modify cleanup behaviour.
+type CleanupOption interface {
+	applyCleanup(params *CleanupParams)
+}
+
+// CleanupParams is a cleanup parameters.
+type CleanupParams struct {
+	// StopOnError stops cleanup on first error.
+	StopOnError bool
+	// SkipSlow is a timeout of each cleanup, zero means no timeout.
+	SkipSlow time.Duration
+}
+
+func (p CleanupParams) applyCleanup(params *CleanupParams) {
+	*params = p
+}
+
+// StopOnError modifies Cleanup() behavior. Cleanup stops on the first failed cleanup and
+// returns its error, remaining cleanups will not be called. By default, all cleanups are
+// called and their errors are joined.
+func StopOnError() CleanupOption {
+	return cleanupOption(func(params *CleanupParams) {
+		params.StopOnError = true
+	})
+}
+
+// SkipSlow modifies Cleanup() behavior. Each cleanup runs in goroutine with timeout d, cleanups
+// that exceed it are skipped and reported in the returned error with ErrCleanupSkipped. It is
+// useful on fast shutdown, when hung Close() must not block the rest of cleanups.
+//
+//	err := container.Cleanup(di.SkipSlow(time.Second))
+//	if errors.Is(err, di.ErrCleanupSkipped) {
+//		// some cleanups were skipped
+//	}
+func SkipSlow(d time.Duration) CleanupOption {
+	return cleanupOption(func(params *CleanupParams) {
+		params.SkipSlow = d
+	})
+}
+
+type cleanupOption func(params *CleanupParams)
+
+func (o cleanupOption) applyCleanup(params *CleanupParams) {
+	o(params)
+}
@@ -10,4 +10,6 @@ type compiler interface {
 	deps(s schema) ([]*node, error)
 	// compile compiles node. The dependencies are already compiled dependencies of this type.
 	compile(dependencies []reflect.Value, s schema) (reflect.Value, error)
+	// dependencies returns types that node depends on without finding them.
+	dependencies() []field
 }
@@ -1,6 +1,7 @@
 package di
 
 import (
+	"fmt"
 	"reflect"
 )
 
@@ -22,15 +23,15 @@ type constructorCompiler struct {
 }
 
 // newConstructorCompiler creates new function compiler from function.
-func newConstructorCompiler(fn function) (*constructorCompiler, bool) {
-	ctorType := determineCtorType(fn)
-	if ctorType == ctorUnknown {
-		return nil, false
+func newConstructorCompiler(fn function) (*constructorCompiler, error) {
+	ctorType, err := determineCtorType(fn)
+	if err != nil {
+		return nil, err
 	}
 	return &constructorCompiler{
 		typ: ctorType,
 		fn:  fn,
-	}, true
+	}, nil
 }
 
 func (c constructorCompiler) deps(s schema) (deps []*node, err error) {
@@ -45,6 +46,14 @@ func (c constructorCompiler) deps(s schema) (deps []*node, err error) {
 	return deps, nil
 }
 
+func (c constructorCompiler) dependencies() []field {
+	var deps []field
+	for i := 0; i < c.fn.NumIn(); i++ {
+		deps = append(deps, field{rt: c.fn.Type.In(i), tags: Tags{}})
+	}
+	return deps
+}
+
 func (c constructorCompiler) compile(dependencies []reflect.Value, s schema) (reflect.Value, error) {
 	// call constructor function
 	out := funcResult(c.fn.Call(dependencies))
@@ -55,32 +64,46 @@ func (c constructorCompiler) compile(dependencies []reflect.Value, s schema) (re
 	case ctorValueError:
 		return rv, out.error(1)
 	case ctorValueCleanup:
-		s.cleanup(out.cleanup())
+		if cleanup := out.cleanup(); cleanup != nil {
+			s.cleanup(&destructor{fn: cleanup})
+		}
 		return rv, nil
 	case ctorValueCleanupError:
-		s.cleanup(out.cleanup())
+		if cleanup := out.cleanup(); cleanup != nil {
+			s.cleanup(&destructor{fn: cleanup})
+		}
 		return rv, out.error(2)
 	}
 	bug()
 	return reflect.Value{}, nil
 }
 
-// determineCtorType
-func determineCtorType(fn function) ctorType {
-	switch true {
-	case fn.NumOut() == 1:
-		return ctorValue
-	case fn.NumOut() == 2:
+// determineCtorType determines constructor type by its results. The error describes
+// what is wrong with unsupported results.
+func determineCtorType(fn function) (ctorType, error) {
+	switch fn.NumOut() {
+	case 0:
+		return ctorUnknown, fmt.Errorf("constructor must return a value")
+	case 1:
+		return ctorValue, nil
+	case 2:
 		if isError(fn.Out(1)) {
-			return ctorValueError
+			return ctorValueError, nil
 		}
 		if isCleanup(fn.Out(1)) {
-			return ctorValueCleanup
+			return ctorValueCleanup, nil
+		}
+		return ctorUnknown, fmt.Errorf("second result must be error or cleanup func(), func() error, func(di.CleanupResolver) or func(di.CleanupResolver) error, got %s", fn.Out(1))
+	case 3:
+		if !isCleanup(fn.Out(1)) {
+			return ctorUnknown, fmt.Errorf("second result must be cleanup func(), func() error, func(di.CleanupResolver) or func(di.CleanupResolver) error, got %s", fn.Out(1))
 		}
-	case fn.NumOut() == 3 && isCleanup(fn.Out(1)) && isError(fn.Out(2)):
-		return ctorValueCleanupError
+		if !isError(fn.Out(2)) {
+			return ctorUnknown, fmt.Errorf("third result must be error, got %s", fn.Out(2))
+		}
+		return ctorValueCleanupError, nil
 	}
-	return ctorUnknown
+	return ctorUnknown, fmt.Errorf("constructor must return at most 3 values: result, cleanup and error, got %d", fn.NumOut())
 }
 
 // funcResult is a helper struct for reflect.Call.
@@ -91,12 +114,32 @@ func (r funcResult) value() reflect.Value {
 	return r[0]
 }
 
-// cleanup returns cleanup function.
-func (r funcResult) cleanup() func() {
+// cleanup returns cleanup function. Cleanups without resolver parameter or error result
+// are wrapped into function with both.
+func (r funcResult) cleanup() func(CleanupResolver) error {
 	if r[1].IsNil() {
 		return nil
 	}
-	return r[1].Interface().(func())
+	switch cleanup := r[1].Interface().(type) {
+	case func(CleanupResolver) error:
+		return cleanup
+	case func(CleanupResolver):
+		return func(r CleanupResolver) error {
+			cleanup(r)
+			return nil
+		}
+	case func() error:
+		return func(CleanupResolver) error {
+			return cleanup()
+		}
+	case func():
+		return func(CleanupResolver) error {
+			cleanup()
+			return nil
+		}
+	}
+	bug()
+	return nil
 }
 
 // error returns error if it exists.
@@ -0,0 +1,68 @@
+package di
+
+import (
+	"reflect"
+)
+
+// factoryCompiler compiles factory function that resolves type from container on each call.
+type factoryCompiler struct {
+	rt   reflect.Type
+	tags Tags
+}
+
+// newFactoryCompiler creates compiler of factory function type rt.
+func newFactoryCompiler(rt reflect.Type, tags Tags) *factoryCompiler {
+	return &factoryCompiler{
+		rt:   rt,
+		tags: tags,
+	}
+}
+
+// isFactory checks that t is a factory function signature: func() T or func() (T, error).
+func isFactory(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.NumIn() != 0 || t.IsVariadic() {
+		return false
+	}
+	return t.NumOut() == 1 || t.NumOut() == 2 && t.Out(1) == errorInterface
+}
+
+func (c *factoryCompiler) deps(s schema) ([]*node, error) {
+	// factory resolves type lazily
+	return nil, nil
+}
+
+func (c *factoryCompiler) dependencies() []field {
+	return []field{{rt: c.rt.Out(0), tags: c.tags}}
+}
+
+func (c *factoryCompiler) compile(dependencies []reflect.Value, s schema) (reflect.Value, error) {
+	fn := reflect.MakeFunc(c.rt, func(args []reflect.Value) []reflect.Value {
+		rv, err := c.resolve(s)
+		if c.rt.NumOut() == 1 {
+			if err != nil {
+				panic(err)
+			}
+			return []reflect.Value{rv}
+		}
+		errValue := reflect.Zero(errorInterface)
+		if err != nil {
+			rv = reflect.Zero(c.rt.Out(0))
+			errValue = reflect.ValueOf(&err).Elem()
+		}
+		return []reflect.Value{rv, errValue}
+	})
+	return fn, nil
+}
+
+// resolve resolves factory result type.
+func (c *factoryCompiler) resolve(s schema) (reflect.Value, error) {
+	n, err := s.find(c.rt.Out(0), c.tags)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	var order []*node
+	if err := visit(s, n, map[*node]int{}, &order); err != nil {
+		return reflect.Value{}, err
+	}
+	return n.Value(s)
+}
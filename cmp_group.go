@@ -2,18 +2,28 @@ package di
 
 import (
 	"reflect"
+	"sync"
 )
 
 type groupCompiler struct {
 	rt      reflect.Type
 	matched []*node
+	// skipNil drops nil members from group
+	skipNil bool
 }
 
-// newGroupCompiler creates group compiler of rt and with matched nodes.
+// newGroupCompiler creates group compiler of rt and with matched nodes. Nodes
+// which membership condition is not met are excluded from the group.
 func newGroupCompiler(rt reflect.Type, matched []*node) *groupCompiler {
+	members := make([]*node, 0, len(matched))
+	for _, n := range matched {
+		if n.condition.met() {
+			members = append(members, n)
+		}
+	}
 	return &groupCompiler{
 		rt:      rt,
-		matched: matched,
+		matched: members,
 	}
 }
 
@@ -21,6 +31,58 @@ func (c *groupCompiler) deps(s schema) (deps []*node, err error) {
 	return c.matched, nil
 }
 
+func (c *groupCompiler) dependencies() []field {
+	deps := make([]field, 0, len(c.matched))
+	for _, n := range c.matched {
+		deps = append(deps, field{rt: n.rt, tags: n.tags})
+	}
+	return deps
+}
+
 func (c *groupCompiler) compile(dependencies []reflect.Value, s schema) (reflect.Value, error) {
-	return reflect.Append(reflect.New(c.rt).Elem(), dependencies...), nil
+	if c.skipNil {
+		members := make([]reflect.Value, 0, len(dependencies))
+		for _, dep := range dependencies {
+			if isNilMember(dep) {
+				continue
+			}
+			members = append(members, dep)
+		}
+		dependencies = members
+	}
+	return reflect.Append(reflect.MakeSlice(c.rt, 0, len(dependencies)), dependencies...), nil
+}
+
+// newEmptyGroupNode creates node of group rt without members.
+func newEmptyGroupNode(rt reflect.Type, tags Tags) *node {
+	return &node{
+		compiler: newGroupCompiler(rt, nil),
+		rt:       rt,
+		tags:     tags,
+		rv:       new(reflect.Value),
+		mu:       new(sync.Mutex),
+	}
+}
+
+// condition is a group membership condition. It is evaluated once on first group build.
+type condition struct {
+	once sync.Once
+	fn   func() bool
+	ok   bool
+}
+
+// met checks that condition is met. Nil condition is always met.
+func (c *condition) met() bool {
+	if c == nil {
+		return true
+	}
+	c.once.Do(func() {
+		c.ok = c.fn()
+	})
+	return c.ok
+}
+
+// isNilMember checks that group member is nil pointer or interface holding nil.
+func isNilMember(rv reflect.Value) bool {
+	return isNil(rv) || rv.Kind() == reflect.Interface && isNil(rv.Elem())
 }
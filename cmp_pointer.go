@@ -0,0 +1,33 @@
+package di
+
+import (
+	"reflect"
+)
+
+// pointerCompiler compiles pointer to interface from interface binding.
+type pointerCompiler struct {
+	rt      reflect.Type
+	binding *node
+}
+
+// newPointerCompiler creates compiler of pointer rt to interface binding.
+func newPointerCompiler(rt reflect.Type, binding *node) *pointerCompiler {
+	return &pointerCompiler{
+		rt:      rt,
+		binding: binding,
+	}
+}
+
+func (c *pointerCompiler) deps(s schema) ([]*node, error) {
+	return []*node{c.binding}, nil
+}
+
+func (c *pointerCompiler) dependencies() []field {
+	return []field{{rt: c.binding.rt, tags: c.binding.tags}}
+}
+
+func (c *pointerCompiler) compile(dependencies []reflect.Value, s schema) (reflect.Value, error) {
+	ptr := reflect.New(c.rt.Elem())
+	ptr.Elem().Set(dependencies[0])
+	return ptr, nil
+}
@@ -19,6 +19,11 @@ func (c typeCompiler) deps(s schema) (deps []*node, err error) {
 	return nil, nil
 }
 
+func (c typeCompiler) dependencies() []field {
+	// fields are dependencies of node
+	return nil
+}
+
 func (c typeCompiler) compile(dependencies []reflect.Value, s schema) (reflect.Value, error) {
 	if c.rt.Kind() == reflect.Ptr {
 		rt := c.rt.Elem()
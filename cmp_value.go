@@ -12,6 +12,10 @@ func (v valueCompiler) deps(s schema) ([]*node, error) {
 	return nil, nil
 }
 
+func (v valueCompiler) dependencies() []field {
+	return nil
+}
+
 func (v valueCompiler) compile(dependencies []reflect.Value, s schema) (reflect.Value, error) {
 	return v.rv, nil
 }
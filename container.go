@@ -1,17 +1,24 @@
 package di
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 )
 
 // Container is a dependency injection container.
 type Container struct {
 	// Dependency injection schema.
 	schema *defaultSchema
+	// laters counts di.ProvideLater() functions in progress
+	laters int
 	// Array of provider cleanups.
 	cleanups []func()
+	// tokens of applied di.ProvideOnce() options
+	tokens map[string]bool
 }
 
 // New constructs container with provided options. Example usage (simplified):
@@ -46,8 +53,9 @@ func New(options ...Option) (_ *Container, err error) {
 	c := &Container{
 		schema:   newDefaultSchema(),
 		cleanups: []func(){},
+		tokens:   map[string]bool{},
 	}
-	var di diopts
+	di := diopts{tokens: c.tokens}
 	// apply container diopts
 	for _, opt := range options {
 		opt.apply(&di)
@@ -69,7 +77,7 @@ func New(options ...Option) (_ *Container, err error) {
 //		// handle error
 //	}
 func (c *Container) Apply(options ...Option) error {
-	var di diopts
+	di := diopts{tokens: c.tokens}
 	for _, opt := range options {
 		opt.apply(&di)
 	}
@@ -94,6 +102,22 @@ func (c *Container) ProvideValue(value Value, options ...ProvideOption) error {
 	return nil
 }
 
+// ProvideNamedValue provides value as is with name tag. See di.ProvideNamedValue() for details.
+func (c *Container) ProvideNamedValue(name string, value Value, options ...ProvideOption) error {
+	if err := c.provideValue(value, append(options, Tags{"name": name})...); err != nil {
+		return errWithStack(err)
+	}
+	return nil
+}
+
+// ProvideStruct provides constructors from module struct fields. See di.ProvideStruct() for details.
+func (c *Container) ProvideStruct(module interface{}, options ...ProvideOption) error {
+	if err := c.provideStruct(module, options...); err != nil {
+		return errWithStack(err)
+	}
+	return nil
+}
+
 // Invocation is a function whose signature looks like:
 //
 //	func StartServer(server *http.Server) error {
@@ -108,7 +132,7 @@ type Invocation interface{}
 // Invoke calls the function fn. It parses function parameters. Looks for it in a container.
 // And invokes function with them. See Invocation for details.
 func (c *Container) Invoke(invocation Invocation, options ...InvokeOption) error {
-	err := c.invoke(invocation, options...)
+	err := c.invoke(context.Background(), invocation, options...)
 	if err != nil && knownError(err) {
 		return errWithStack(err)
 	}
@@ -118,6 +142,23 @@ func (c *Container) Invoke(invocation Invocation, options ...InvokeOption) error
 	return nil
 }
 
+// InvokeContext calls the function fn like Invoke(), but aborts building of its dependencies
+// when ctx is done and returns ctx.Err(). The context is checked between type constructions,
+// a running constructor is not interrupted.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	err := container.InvokeContext(ctx, func(server *http.Server) error {
+//		return server.ListenAndServe()
+//	})
+func (c *Container) InvokeContext(ctx context.Context, invocation Invocation, options ...InvokeOption) error {
+	err := c.invoke(ctx, invocation, options...)
+	if err != nil && knownError(err) {
+		return errWithStack(err)
+	}
+	return err
+}
+
 type Pointer interface{}
 
 // Has checks that type exists in container, if not it return false.
@@ -137,6 +178,99 @@ func (c *Container) Has(target Pointer, options ...ResolveOption) (bool, error)
 	return true, nil
 }
 
+// Inspect returns definitions of types provided to container and its ancestors in order of
+// provide. Definitions are read-only description of the container that can be used to write
+// custom checks:
+//
+//	for _, def := range container.Inspect() {
+//		for _, dep := range def.Dependencies {
+//			if dep.Type == reflect.TypeOf(new(sql.DB)) {
+//				// handle direct database dependency
+//			}
+//		}
+//	}
+func (c *Container) Inspect() []Definition {
+	return c.schema.definitions()
+}
+
+// UseInterceptor adds interceptor that wraps construction of every type. Interceptors are
+// called in order of adding, the first one is the outermost.
+//
+//	container.UseInterceptor(func(next di.ConstructFunc) di.ConstructFunc {
+//		return func(info di.ConstructInfo) (reflect.Value, error) {
+//			log.Printf("building %s", info.Type)
+//			return next(info)
+//		}
+//	})
+func (c *Container) UseInterceptor(interceptor Interceptor) {
+	c.schema.use(interceptor)
+}
+
+// String returns human-readable summary of provided types sorted by type name: tags, lifetime,
+// count of dependencies and groups the type is member of.
+func (c *Container) String() string {
+	return c.schema.summary()
+}
+
+// ResolveByName resolves provided type by its name and returns the instance. The name is a type
+// string, like *http.Server, or the type string with full package path, like *net/http.Server.
+// Only provided types can be resolved, it causes error if the name is unknown or matches
+// several types, like types of different packages with the same name.
+//
+//	instance, err := container.ResolveByName("*http.Server", di.Name("public"))
+func (c *Container) ResolveByName(name string, options ...ResolveOption) (interface{}, error) {
+	t, err := c.schema.typeByName(name)
+	if err != nil {
+		return nil, errWithStack(err)
+	}
+	target := reflect.New(t)
+	if err := c.resolve(target.Interface(), options...); err != nil {
+		return nil, errWithStack(err)
+	}
+	return target.Elem().Interface(), nil
+}
+
+// Provider is a constructor with provide options.
+type Provider struct {
+	Constructor Constructor
+	Options     []ProvideOption
+}
+
+// SetProviders atomically replaces all types t, including their di.As() aliases, with types of
+// providers. Resolving concurrently sees either the old or the new set of types, never a mix.
+// Cached instances of the replaced types and all of their dependents are cleared. It is useful
+// for hot-swapping of plugins:
+//
+//	err := container.SetProviders(reflect.TypeOf(new(Plugin)).Elem(), []di.Provider{
+//		{Constructor: NewAuthPlugin, Options: []di.ProvideOption{di.As(new(Plugin))}},
+//		{Constructor: NewLogPlugin, Options: []di.ProvideOption{di.As(new(Plugin))}},
+//	})
+func (c *Container) SetProviders(t reflect.Type, providers []Provider) error {
+	if err := c.setProviders(t, providers); err != nil {
+		return errWithStack(err)
+	}
+	return nil
+}
+
+// AliasTags makes provided type of target pointer with tags from also answer to tags to. The
+// alias shares instance with the type, like di.As() does. It is useful when subsystems query
+// types by different tag conventions:
+//
+//	// primary database is a writer too
+//	err := container.AliasTags(new(*sql.DB), di.Tags{"name": "primary"}, di.Tags{"role": "writer"})
+func (c *Container) AliasTags(target Pointer, from, to Tags) error {
+	if err := c.aliasTags(target, from, to); err != nil {
+		return errWithStack(err)
+	}
+	return nil
+}
+
+// Types returns types provided to container and its ancestors in order of provide. Types
+// of di.Inject structs created on resolve are not included.
+func (c *Container) Types() []reflect.Type {
+	return c.schema.types()
+}
+
 // Resolve resolves type and fills target pointer.
 //
 //	var server *http.Server
@@ -150,6 +284,31 @@ func (c *Container) Resolve(ptr Pointer, options ...ResolveOption) error {
 	return nil
 }
 
+// ResolutionOrder returns types that will be built to resolve target in order of their
+// construction. Dependencies go before the types that depend on them, the target type is
+// the last one. The order is the same that is used by Resolve() and Build().
+//
+//	var server *http.Server
+//	order, err := container.ResolutionOrder(&server)
+//	if err != nil {
+//		// handle error
+//	}
+func (c *Container) ResolutionOrder(target Pointer, options ...ResolveOption) ([]reflect.Type, error) {
+	node, err := c.find(target, options...)
+	if err != nil {
+		return nil, errWithStack(err)
+	}
+	order, err := c.schema.sort(node)
+	if err != nil {
+		return nil, errWithStack(err)
+	}
+	types := make([]reflect.Type, 0, len(order))
+	for _, n := range order {
+		types = append(types, n.rt)
+	}
+	return types, nil
+}
+
 // ValueFunc is a lazy-loading wrapper for iteration.
 type ValueFunc func() (interface{}, error)
 
@@ -192,11 +351,161 @@ func (c *Container) Iterate(target Pointer, fn IterateFunc, options ...ResolveOp
 	return fmt.Errorf("iteration can be used with groups only")
 }
 
-// Cleanup runs destructors in reverse order that was been created.
-func (c *Container) Cleanup() {
-	for i := len(c.schema.cleanups) - 1; i >= 0; i-- {
-		c.schema.cleanups[i]()
+// Cleanup runs destructors in reverse order that was been created. Cleanup function
+// of constructor may return an error. By default, all destructors are called and
+// their errors are joined. Use di.StopOnError() to stop on the first error and di.SkipSlow()
+// to skip cleanups that exceed timeout.
+func (c *Container) Cleanup(options ...CleanupOption) error {
+	params := CleanupParams{}
+	for _, opt := range options {
+		opt.applyCleanup(&params)
+	}
+	var errs []error
+	cleanups := c.schema.destructors()
+	resolver := &cleanupResolver{container: c, cleaned: map[*reflect.Value]bool{}}
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		var err error
+		if params.SkipSlow > 0 {
+			err = cleanups[i].runWithin(resolver, params.SkipSlow)
+		} else {
+			err = cleanups[i].run(resolver)
+		}
+		resolver.clean(cleanups[i].node)
+		if err != nil && params.StopOnError {
+			return err
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return joinErrors(errs)
+}
+
+// CacheStats returns statistics of the container instance cache. Each request of type instance
+// during resolving is counted: hits returned instance that was already built, misses built
+// a new one.
+func (c *Container) CacheStats() CacheStats {
+	return c.schema.cacheStats()
+}
+
+// Warm builds targets and all of their dependencies. Independent dependencies are built
+// concurrently, each type is built once after its dependencies. Targets are pointers like in
+// Resolve(), they are used to determine types and are not filled. It is useful to start
+// types with slow construction in parallel, like connection pools.
+//
+//	err := container.Warm(new(*sql.DB), new(*redis.Client))
+//	if err != nil {
+//		// handle error
+//	}
+func (c *Container) Warm(targets ...Pointer) error {
+	var nodes []*node
+	for _, target := range targets {
+		node, err := c.find(target)
+		if err != nil {
+			return errWithStack(err)
+		}
+		nodes = append(nodes, node)
+	}
+	order, err := c.schema.sort(nodes...)
+	if err != nil {
+		return errWithStack(err)
+	}
+	order = prebuilt(order)
+	// dependents wait for results of their dependencies, so failed dependency is built once
+	results := make(map[*node]*warmResult, len(order))
+	for _, n := range order {
+		results[n] = &warmResult{done: make(chan struct{})}
+	}
+	var wg sync.WaitGroup
+	for _, n := range order {
+		deps, err := c.schema.sort(n)
+		if err != nil {
+			return errWithStack(err)
+		}
+		wg.Add(1)
+		go func(n *node, deps []*node) {
+			defer wg.Done()
+			result := results[n]
+			defer close(result.done)
+			for _, dep := range deps {
+				waited, ok := results[dep]
+				if !ok || dep == n {
+					continue
+				}
+				<-waited.done
+				if waited.err != nil {
+					result.err = waited.err
+					return
+				}
+			}
+			if _, err := n.Value(c.schema); err != nil {
+				result.err = fmt.Errorf("%s: %w", n, err)
+			}
+		}(n, deps)
+	}
+	wg.Wait()
+	// the first error in topological order is a cause of dependent errors
+	for _, n := range order {
+		if err := results[n].err; err != nil {
+			return errWithStack(err)
+		}
+	}
+	return nil
+}
+
+// warmResult is a result of node build by Warm(). Done is closed when the node is built.
+type warmResult struct {
+	done chan struct{}
+	err  error
+}
+
+// Invalidate clears cached instances of type t that was provided with di.Volatile() option and
+// instances of all types that depend on it. They will be rebuilt on next resolve. Cleanups of
+// invalidated instances are not called, they will be called on container cleanup.
+//
+//	// configuration was changed
+//	if err := container.Invalidate(reflect.TypeOf(&Config{})); err != nil {
+//		// handle error
+//	}
+func (c *Container) Invalidate(t reflect.Type) error {
+	if err := c.schema.invalidate(t); err != nil {
+		return errWithStack(err)
+	}
+	return nil
+}
+
+// Graph returns dependency graph of container and its parents types. Missing dependencies and
+// multiple definitions are presented as nodes with error. The types are not built.
+//
+//	fmt.Println(container.Graph().DOT())
+func (c *Container) Graph() *Graph {
+	return c.schema.graph()
+}
+
+// Validate checks the whole graph of container types without building them. All missing
+// dependencies and multiple definitions are reported at once, cycles are reported after
+// them. Missing dependencies of scoped and transient types are not reported, scopes may provide
+// them. It is useful for CI checks, use di.FailFast() option to validate graph on build.
+//
+//	if err := container.Validate(); err != nil {
+//		// handle error
+//	}
+func (c *Container) Validate() error {
+	if err := c.schema.validate(); err != nil {
+		return errWithStack(err)
+	}
+	return nil
+}
+
+// Build builds all types that were provided with di.Eager() option. The types are built
+// in topological order: dependencies go first and are built once. Build is called automatically
+// by di.New() and Apply() after processing of provides. With di.FailFast() option, Build
+// validates the whole graph first.
+func (c *Container) Build() error {
+	if err := c.build(); err != nil {
+		return errWithStack(err)
 	}
+	return nil
 }
 
 // AddParent adds a parent container. Types are resolved from the container,
@@ -206,21 +515,70 @@ func (c *Container) AddParent(parent *Container) error {
 }
 
 func (c *Container) apply(di diopts) error {
+	if di.preferLast {
+		c.schema.preferLast = true
+	}
+	if di.noInjectCache {
+		c.schema.noInjectCache = true
+	}
+	if di.removeOnNil {
+		c.schema.removeOnNil = true
+	}
+	if di.disallowNil || di.strict {
+		c.schema.nilDisallowed = true
+	}
+	if di.strict {
+		c.schema.strict = true
+	}
+	if di.lateRegistration {
+		c.schema.lateRegistration = true
+	}
+	if di.failFast {
+		c.schema.failFast = true
+	}
+	if di.weightSeed != nil {
+		c.schema.seed(*di.weightSeed)
+	}
+	if di.scope != "" {
+		c.schema.scope = newScopeNode(di.scope)
+	}
+	for _, interceptor := range di.interceptors {
+		c.schema.use(interceptor)
+	}
 	for _, provide := range di.values {
 		if err := c.provideValue(provide.value, provide.options...); err != nil {
 			return fmt.Errorf("%s: %w", provide.frame, err)
 		}
 	}
+	for _, provide := range di.structs {
+		if err := c.provideStruct(provide.module, provide.options...); err != nil {
+			return fmt.Errorf("%s: %w", provide.frame, err)
+		}
+	}
 	// process di.Resolve() diopts
 	for _, provide := range di.provides {
 		if err := c.provide(provide.constructor, provide.options...); err != nil {
 			return fmt.Errorf("%s: %w", provide.frame, err)
 		}
 	}
+	// process di.ProvideLater() diopts after all provides, they provide types after resolve
+	// by design
+	for _, later := range di.laters {
+		c.laters++
+		err := later.fn(c)
+		c.laters--
+		if err != nil {
+			return fmt.Errorf("%s: %w", later.frame, err)
+		}
+	}
+	// build eager types before invocations
+	if err := c.build(); err != nil {
+		return err
+	}
 	// error omitted because if logger could not be resolved it will be default
 	// process di.Invoke() diopts
 	for _, invoke := range di.invokes {
-		err := c.invoke(invoke.fn, invoke.options...)
+		err := c.invoke(context.Background(), invoke.fn, invoke.options...)
 		if err != nil && knownError(err) {
 			return fmt.Errorf("%s: %w", invoke.frame, err)
 		}
@@ -246,15 +604,166 @@ func (c *Container) provide(constructor Constructor, options ...ProvideOption) e
 	for _, opt := range options {
 		opt.applyProvide(&params)
 	}
-	n, err := newConstructorNode(constructor)
+	if rv := reflect.ValueOf(constructor); rv.Kind() == reflect.Func && rv.IsNil() {
+		return c.remove(rv.Type(), params)
+	}
+	n, err := constructorNode(constructor, params)
 	if err != nil {
 		return err
 	}
+	return c.provideNode(n, params)
+}
+
+// aliasTags registers node of target type with tags to that shares instance with the node of
+// tags from.
+func (c *Container) aliasTags(target Pointer, from, to Tags) error {
+	if target == nil || reflect.ValueOf(target).Kind() != reflect.Ptr {
+		return fmt.Errorf("target must be a pointer, got %s", reflect.TypeOf(target))
+	}
+	t := reflect.TypeOf(target).Elem()
+	n, err := c.schema.find(t, from)
+	if err != nil {
+		return err
+	}
+	if !c.schema.registered(n) {
+		return fmt.Errorf("%s can't be aliased, only provided types can", n)
+	}
+	tags := Tags{}
+	for k, v := range to {
+		tags[k] = v
+	}
+	c.schema.register(n.share(t, tags))
+	return nil
+}
+
+// constructorNode creates node of constructor with provide parameters.
+func constructorNode(constructor Constructor, params ProvideParams) (*node, error) {
+	n, err := newConstructorNode(constructor)
+	if err != nil {
+		return nil, err
+	}
 	n.decorators = params.Decorators
+	n.eager = params.Eager
+	n.volatile = params.Volatile
+	n.module = params.module
+	n.labels = params.Labels
+	n.lockThread = params.LockThread
+	n.lifetime = params.Lifetime
+	n.options = provideOptionsKey(params)
+	if n.eager && n.lifetime == LifetimeScoped {
+		return nil, fmt.Errorf("%s is scoped and can't be eager", n)
+	}
+	if params.Phase != "" {
+		n.phase = &phase{name: params.Phase, order: params.PhaseOrder}
+	}
 	for k, v := range params.Tags {
 		n.tags[k] = v
 	}
-	return c.provideNode(n, params)
+	return n, nil
+}
+
+// provideOptionsKey describes provide options except tags. Functions of options are compared
+// by code pointer.
+func provideOptionsKey(params ProvideParams) string {
+	var b strings.Builder
+	for _, i := range params.Interfaces {
+		fmt.Fprintf(&b, "as %s; ", reflect.TypeOf(i))
+	}
+	for _, group := range params.Groups {
+		fmt.Fprintf(&b, "group %s; ", reflect.TypeOf(group))
+	}
+	for _, decorator := range params.Decorators {
+		fmt.Fprintf(&b, "decorator %x; ", reflect.ValueOf(decorator).Pointer())
+	}
+	if params.Condition != nil {
+		fmt.Fprintf(&b, "condition %x; ", reflect.ValueOf(params.Condition).Pointer())
+	}
+	weight := params.Weight
+	if weight == 0 {
+		weight = 1
+	}
+	fmt.Fprintf(&b, "eager %t; volatile %t; lock thread %t; weight %d; lifetime %s; phase %s %d; labels %v; module %s",
+		params.Eager, params.Volatile, params.LockThread, weight, params.Lifetime, params.Phase, params.PhaseOrder, params.Labels, params.module)
+	return b.String()
+}
+
+// setProviders creates nodes of providers and replaces nodes of type t with them.
+func (c *Container) setProviders(t reflect.Type, providers []Provider) error {
+	var nodes []*node
+	for _, provider := range providers {
+		if rv := reflect.ValueOf(provider.Constructor); provider.Constructor == nil || rv.Kind() == reflect.Func && rv.IsNil() {
+			return fmt.Errorf("invalid constructor signature, got nil")
+		}
+		params := ProvideParams{}
+		for _, opt := range provider.Options {
+			opt.applyProvide(&params)
+		}
+		n, err := constructorNode(provider.Constructor, params)
+		if err != nil {
+			return err
+		}
+		provided, err := c.aliases(n, params)
+		if err != nil {
+			return err
+		}
+		var ok bool
+		for _, cur := range provided {
+			ok = ok || cur.rt == t
+		}
+		if !ok {
+			return fmt.Errorf("%s not provide %s", n, t)
+		}
+		nodes = append(nodes, provided...)
+	}
+	c.schema.replace(t, nodes)
+	return nil
+}
+
+// remove removes types of nil constructor of type t.
+func (c *Container) remove(t reflect.Type, params ProvideParams) error {
+	if !c.schema.removeOnNil {
+		return fmt.Errorf("invalid constructor signature, got nil %s: use di.RemoveOnNil() option to remove types with nil constructor", t)
+	}
+	if _, err := determineCtorType(function{Type: t}); err != nil {
+		return fmt.Errorf("invalid constructor signature, got %s: %w", t, err)
+	}
+	c.schema.remove(t.Out(0), params.Tags)
+	return nil
+}
+
+func (c *Container) provideStruct(module interface{}, options ...ProvideOption) error {
+	if module == nil {
+		return fmt.Errorf("invalid module, got nil")
+	}
+	rv := reflect.Indirect(reflect.ValueOf(module))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("invalid module, got %s", reflect.TypeOf(module))
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		if fv.Kind() != reflect.Func {
+			return fmt.Errorf("%s.%s: invalid constructor signature, got %s: constructor must be a function", rt, f.Name, f.Type)
+		}
+		if fv.IsNil() {
+			continue
+		}
+		field, ok := inspectStructField(rt, f)
+		if !ok {
+			continue
+		}
+		module := provideOption(func(params *ProvideParams) {
+			params.module = rt.String()
+		})
+		if err := c.provide(fv.Interface(), append(options[:len(options):len(options)], field.tags, module)...); err != nil {
+			return fmt.Errorf("%s.%s: %w", rt, f.Name, err)
+		}
+	}
+	return nil
 }
 
 func (c *Container) provideValue(value Value, options ...ProvideOption) error {
@@ -272,31 +781,120 @@ func (c *Container) provideValue(value Value, options ...ProvideOption) error {
 			rv: v,
 		},
 		rv:         new(reflect.Value),
+		raw:        new(reflect.Value),
+		mu:         new(sync.Mutex),
 		rt:         v.Type(),
 		tags:       params.Tags,
 		decorators: params.Decorators,
+		eager:      params.Eager,
+		volatile:   params.Volatile,
+		labels:     params.Labels,
 	}
 	return c.provideNode(n, params)
 }
 
 func (c *Container) provideNode(n *node, params ProvideParams) error {
-	c.schema.register(n)
+	if c.laters == 0 && !c.schema.lateRegistration && c.schema.resolved() {
+		return fmt.Errorf("%s provided after types were resolved, instances that are already built will not use it: use di.AllowLateRegistration() option to provide types after resolve", n)
+	}
+	// the same constructor included by several modules is provided once
+	identical, err := c.schema.identical(n)
+	if err != nil {
+		return err
+	}
+	if identical {
+		return nil
+	}
+	nodes, err := c.aliases(n, params)
+	if err != nil {
+		return err
+	}
+	for _, cur := range nodes {
+		c.schema.register(cur)
+	}
+	return nil
+}
+
+// aliases returns node and nodes of its interfaces and groups that share instance with it.
+func (c *Container) aliases(n *node, params ProvideParams) ([]*node, error) {
+	if params.Weight < 0 {
+		return nil, fmt.Errorf("invalid weight of %s, got %d: weight must not be negative", n, params.Weight)
+	}
+	n.weight = params.Weight
+	if n.weight == 0 {
+		n.weight = 1
+	}
+	if params.Condition != nil {
+		n.condition = &condition{fn: params.Condition}
+	}
+	interfaces := params.Interfaces
+	for _, group := range params.Groups {
+		elem, err := groupElem(n, group)
+		if err != nil {
+			return nil, err
+		}
+		if elem != n.rt {
+			interfaces = append(interfaces, reflect.New(elem).Interface())
+		}
+	}
+	nodes := []*node{n}
 	// register interfaces
-	for _, cur := range params.Interfaces {
+	for _, cur := range interfaces {
 		i, err := inspectInterfacePointer(cur)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if !n.rt.Implements(i.Type) {
-			return fmt.Errorf("%s not implement %s", n, i.Type)
-		}
-		c.schema.register(&node{
-			rv:         n.rv,
-			rt:         i.Type,
-			tags:       n.tags,
-			compiler:   n.compiler,
-			decorators: n.decorators,
-		})
+			return nil, fmt.Errorf("%s not implement %s", n, i.Type)
+		}
+		nodes = append(nodes, n.share(i.Type, n.tags))
+	}
+	for _, cur := range nodes {
+		if cur.rt == scopeNameType || cur.rt == registryType {
+			return nil, fmt.Errorf("%s is injected by container and can't be provided", cur)
+		}
+	}
+	return nodes, nil
+}
+
+// groupElem checks that node type can be a member of group and returns group element type.
+func groupElem(n *node, group Pointer) (reflect.Type, error) {
+	if group == nil {
+		return nil, fmt.Errorf("invalid group, got nil")
+	}
+	rt := reflect.TypeOf(group)
+	if rt.Kind() != reflect.Ptr || rt.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("invalid group, must be a pointer to slice, got %s", rt)
+	}
+	elem := rt.Elem().Elem()
+	if elem.Kind() == reflect.Interface && !n.rt.Implements(elem) {
+		return nil, fmt.Errorf("%s not implement %s, can't be a member of group %s", n, elem, rt.Elem())
+	}
+	if elem.Kind() != reflect.Interface && n.rt != elem {
+		return nil, fmt.Errorf("%s can't be a member of group %s", n, rt.Elem())
+	}
+	return elem, nil
+}
+
+func (c *Container) groupSize(t reflect.Type, options ...ResolveOption) (int, error) {
+	return c.schema.groupSize(t, resolveParams(options...))
+}
+
+func (c *Container) build() error {
+	if c.schema.failFast {
+		if err := c.schema.validate(); err != nil {
+			return err
+		}
+	}
+	eager := c.schema.eager()
+	order, err := c.schema.sort(eager...)
+	if err != nil {
+		return err
+	}
+	for _, node := range prebuilt(order, eager...) {
+		if _, err := node.Value(c.schema); err != nil {
+			return fmt.Errorf("%s: %w", node, err)
+		}
 	}
 	return nil
 }
@@ -306,7 +904,12 @@ func (c *Container) resolve(ptr Pointer, options ...ResolveOption) error {
 	if err != nil {
 		return err
 	}
-	value, err := node.Value(c.schema)
+	var value reflect.Value
+	if resolveParams(options...).Undecorated {
+		value, err = node.Undecorated(c.schema)
+	} else {
+		value, err = node.Value(c.schema)
+	}
 	if err != nil {
 		return fmt.Errorf("%s: %w", node, err)
 	}
@@ -322,11 +925,11 @@ func (c *Container) resolve(ptr Pointer, options ...ResolveOption) error {
 	return nil
 }
 
-func (c *Container) invoke(invocation Invocation, _ ...InvokeOption) error {
-	// params := InvokeParams{}
-	// for _, opt := range diopts {
-	// 	opt.apply(&params)
-	// }
+func (c *Container) invoke(ctx context.Context, invocation Invocation, options ...InvokeOption) error {
+	params := InvokeParams{}
+	for _, opt := range options {
+		opt.apply(&params)
+	}
 	if invocation == nil {
 		return fmt.Errorf("%w, got %s", errInvalidInvocationSignature, "nil")
 	}
@@ -337,21 +940,59 @@ func (c *Container) invoke(invocation Invocation, _ ...InvokeOption) error {
 	if !validateInvocation(fn) {
 		return fmt.Errorf("%w, got %s", errInvalidInvocationSignature, reflect.TypeOf(invocation))
 	}
-	nodes, err := parseInvocationParameters(fn, c.schema)
+	for _, value := range params.Values {
+		if value == nil {
+			return fmt.Errorf("invalid invocation value, got nil")
+		}
+	}
+	nodes, err := parseInvocationParameters(fn, c.schema, params.Values)
 	if err != nil {
 		return err
 	}
-	var args []reflect.Value
 	for _, node := range nodes {
 		if err := c.schema.prepare(node); err != nil {
 			return err
 		}
-		v, err := node.Value(c.schema)
+	}
+	var s schema = c.schema
+	// values are pre-built dependencies, arguments reuse them to record the resolve once
+	values := map[*node]reflect.Value{}
+	if params.trace != nil {
+		// traced dependencies are built by arguments to record the tree of their dependencies
+		s = newTraceSchema(c.schema, params.trace)
+	} else {
+		// build dependencies one by one to check context between constructions
+		order, err := c.schema.sort(nodes...)
+		if err != nil {
+			return err
+		}
+		// transient arguments are built once with arguments
+		for _, node := range prebuilt(order) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			v, err := node.Value(c.schema)
+			if err != nil {
+				return fmt.Errorf("%s: %s", node, err)
+			}
+			values[node] = v
+		}
+	}
+	var args []reflect.Value
+	for _, node := range nodes {
+		if v, ok := values[node]; ok {
+			args = append(args, v)
+			continue
+		}
+		v, err := node.Value(s)
 		if err != nil {
 			return fmt.Errorf("%s: %s", node, err)
 		}
 		args = append(args, v)
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	res := funcResult(fn.Call(args))
 	if len(res) == 0 {
 		return nil
@@ -366,19 +1007,7 @@ func (c *Container) find(ptr Pointer, options ...ResolveOption) (*node, error) {
 	if reflect.ValueOf(ptr).Kind() != reflect.Ptr {
 		return nil, fmt.Errorf("target must be a pointer, got %s", reflect.TypeOf(ptr))
 	}
-	params := ResolveParams{}
-	// apply extract diopts
-	for _, opt := range options {
-		opt.applyResolve(&params)
-	}
-	node, err := c.schema.find(reflect.TypeOf(ptr).Elem(), params.Tags)
-	if err != nil {
-		return nil, err
-	}
-	if err := c.schema.prepare(node); err != nil {
-		return nil, err
-	}
-	return node, nil
+	return c.schema.pick(reflect.TypeOf(ptr).Elem(), resolveParams(options...))
 }
 
 type diopts struct {
@@ -386,8 +1015,34 @@ type diopts struct {
 	provides []provideOptions
 	// Array of di.ProvideValue() options.
 	values []provideValueOptions
+	// Array of di.ProvideStruct() options.
+	structs []provideStructOptions
 	// Array of di.Invoke() options.
 	invokes []invokeOptions
 	// Array of di.Resolve() options.
 	resolves []resolveOptions
+	// Array of di.ProvideLater() options.
+	laters []provideLaterOptions
+	// Array of di.OnResolve() interceptors.
+	interceptors []Interceptor
+	// di.PreferLast() option.
+	preferLast bool
+	// di.Scope() option.
+	scope ScopeName
+	// di.NoInjectCache() option.
+	noInjectCache bool
+	// di.RemoveOnNil() option.
+	removeOnNil bool
+	// tokens of applied di.ProvideOnce() options, shared with container
+	tokens map[string]bool
+	// di.DisallowNil() option.
+	disallowNil bool
+	// di.Strict() option.
+	strict bool
+	// di.AllowLateRegistration() option.
+	lateRegistration bool
+	// di.WeightSeed() option.
+	weightSeed *int64
+	// di.FailFast() option.
+	failFast bool
 }
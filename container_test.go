@@ -1,13 +1,21 @@
 package di_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -47,7 +55,7 @@ func TestContainer_Provide(t *testing.T) {
 		err = c.Provide("string")
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "container_test.go:")
-		require.Contains(t, err.Error(), ": invalid constructor signature, got string")
+		require.Contains(t, err.Error(), ": invalid constructor signature, got string: constructor must be a function")
 	})
 
 	t.Run("provide nil cause error", func(t *testing.T) {
@@ -65,7 +73,7 @@ func TestContainer_Provide(t *testing.T) {
 		err = c.Provide(&http.Server{})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "container_test.go:")
-		require.Contains(t, err.Error(), ": invalid constructor signature, got *http.Server")
+		require.Contains(t, err.Error(), ": invalid constructor signature, got *http.Server: constructor must be a function")
 	})
 
 	t.Run("provide constructor without result cause error", func(t *testing.T) {
@@ -74,7 +82,7 @@ func TestContainer_Provide(t *testing.T) {
 		err = c.Provide(func() {})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "container_test.go:")
-		require.Contains(t, err.Error(), ": invalid constructor signature, got func()")
+		require.Contains(t, err.Error(), ": invalid constructor signature, got func(): constructor must return a value")
 	})
 
 	t.Run("provide constructor with many resultant types cause error", func(t *testing.T) {
@@ -86,7 +94,7 @@ func TestContainer_Provide(t *testing.T) {
 		err = c.Provide(ctor)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "container_test.go:")
-		require.Contains(t, err.Error(), ": invalid constructor signature, got func() (*http.Server, *http.ServeMux, error)")
+		require.Contains(t, err.Error(), ": invalid constructor signature, got func() (*http.Server, *http.ServeMux, error): second result must be cleanup func(), func() error, func(di.CleanupResolver) or func(di.CleanupResolver) error, got *http.ServeMux")
 	})
 
 	t.Run("provide constructor with incorrect result error", func(t *testing.T) {
@@ -98,7 +106,29 @@ func TestContainer_Provide(t *testing.T) {
 		err = c.Provide(ctor)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "container_test.go:")
-		require.Contains(t, err.Error(), "invalid constructor signature, got func() (*http.Server, *http.ServeMux)")
+		require.Contains(t, err.Error(), "invalid constructor signature, got func() (*http.Server, *http.ServeMux): second result must be error or cleanup func(), func() error, func(di.CleanupResolver) or func(di.CleanupResolver) error, got *http.ServeMux")
+	})
+
+	t.Run("provide constructor with incorrect third result cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		ctor := func() (*http.Server, func(), *http.ServeMux) {
+			return nil, nil, nil
+		}
+		err = c.Provide(ctor)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid constructor signature, got func() (*http.Server, func(), *http.ServeMux): third result must be error, got *http.ServeMux")
+	})
+
+	t.Run("provide constructor with four results cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		ctor := func() (*http.Server, *http.ServeMux, func(), error) {
+			return nil, nil, nil, nil
+		}
+		err = c.Provide(ctor)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "constructor must return at most 3 values: result, cleanup and error, got 4")
 	})
 
 	t.Run("provide duplicate not cause error", func(t *testing.T) {
@@ -150,6 +180,112 @@ func TestContainer_Provide(t *testing.T) {
 		require.Contains(t, err.Error(), "container_test.go:")
 		require.Contains(t, err.Error(), ": nil: not a pointer to interface")
 	})
+
+	t.Run("provide nil constructor cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		var ctor func() *http.Server
+		err = c.Provide(ctor)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), ": invalid constructor signature, got nil func() *http.Server: use di.RemoveOnNil() option to remove types with nil constructor")
+	})
+
+	t.Run("provide nil constructor removes type", func(t *testing.T) {
+		var ctor func() *http.Server
+		c, err := di.New(
+			di.RemoveOnNil(),
+			di.Provide(func() *http.Server { return &http.Server{} }, di.As(new(io.Closer))),
+			di.Provide(func() *http.Server { return &http.Server{} }, di.Tags{"name": "public"}),
+			di.Provide(ctor, di.Tags{"name": "public"}),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		var closer io.Closer
+		require.NoError(t, c.Resolve(&closer))
+		require.NoError(t, c.Provide(ctor))
+		has, err := c.Has(&server)
+		require.NoError(t, err)
+		require.False(t, has)
+		has, err = c.Has(&closer)
+		require.NoError(t, err)
+		require.False(t, has)
+	})
+
+	t.Run("provide after resolve cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.Provide(func() *http.Server { return &http.Server{} }))
+		var mux *http.ServeMux
+		require.NoError(t, c.Resolve(&mux))
+		err = c.Provide(func() *net.TCPConn { return &net.TCPConn{} })
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*net.TCPConn provided after types were resolved, instances that are already built will not use it: use di.AllowLateRegistration() option to provide types after resolve")
+		has, err := c.Has(new(*net.TCPConn))
+		require.NoError(t, err)
+		require.False(t, has)
+	})
+
+	t.Run("provide after resolve with allowed late registration", func(t *testing.T) {
+		c, err := di.New(
+			di.AllowLateRegistration(),
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+		)
+		require.NoError(t, err)
+		var mux *http.ServeMux
+		require.NoError(t, c.Resolve(&mux))
+		require.NoError(t, c.Provide(func() *net.TCPConn { return &net.TCPConn{} }))
+		var conn *net.TCPConn
+		require.NoError(t, c.Resolve(&conn))
+	})
+
+	t.Run("the same constructor of several modules is provided once", func(t *testing.T) {
+		var calls int
+		newServer := func() *http.Server {
+			calls++
+			return &http.Server{}
+		}
+		base := func() di.Option {
+			return di.Provide(newServer)
+		}
+		c, err := di.New(
+			di.Options(base(), di.Provide(http.NewServeMux)),
+			di.Options(base(), di.Provide(http.NewServeMux, di.WithName("second"))),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		require.Equal(t, 1, calls)
+		var muxs []*http.ServeMux
+		require.NoError(t, c.Resolve(&muxs))
+		require.Len(t, muxs, 2)
+	})
+
+	t.Run("closures of the same function literal with other options are different constructors", func(t *testing.T) {
+		var options []di.Option
+		for i, addr := range []string{":1", ":2"} {
+			addr := addr
+			options = append(options, di.Provide(func() *http.Server { return &http.Server{Addr: addr} }, di.Weight(i+1)))
+		}
+		c, err := di.New(options...)
+		require.NoError(t, err)
+		var servers []*http.Server
+		require.NoError(t, c.Resolve(&servers))
+		require.Len(t, servers, 2)
+		require.Equal(t, ":1", servers[0].Addr)
+		require.Equal(t, ":2", servers[1].Addr)
+	})
+
+	t.Run("the same constructor with other options cause error", func(t *testing.T) {
+		_, err := di.New(
+			di.Provide(http.NewServeMux),
+			di.Provide(http.NewServeMux, di.As(new(http.Handler))),
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*http.ServeMux is already provided by the same constructor with other options")
+	})
 }
 
 func TestContainer_ProvideValue(t *testing.T) {
@@ -204,6 +340,44 @@ func TestContainer_ProvideValue(t *testing.T) {
 		require.Contains(t, err.Error(), "container_test.go:")
 		require.Contains(t, err.Error(), "invalid value, got nil")
 	})
+
+	t.Run("provide named values of same type", func(t *testing.T) {
+		type Timeouts struct {
+			di.Inject
+			Read  time.Duration `di:"name=read"`
+			Write time.Duration `di:"name=write"`
+		}
+		c, err := di.New(
+			di.ProvideNamedValue("read", 5*time.Second),
+			di.ProvideNamedValue("write", 10*time.Second),
+		)
+		require.NoError(t, err)
+		var timeouts Timeouts
+		require.NoError(t, c.Resolve(&timeouts))
+		require.Equal(t, 5*time.Second, timeouts.Read)
+		require.Equal(t, 10*time.Second, timeouts.Write)
+		var timeout time.Duration
+		require.NoError(t, c.Resolve(&timeout, di.Name("write")))
+		require.Equal(t, 10*time.Second, timeout)
+		err = c.Resolve(&timeout)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), ": multiple definitions of time.Duration")
+	})
+
+	t.Run("provide named value into constructor parameters", func(t *testing.T) {
+		type Params struct {
+			di.Inject
+			Addr string `di:"name=addr"`
+		}
+		c, err := di.New()
+		require.NoError(t, err)
+		require.NoError(t, c.ProvideNamedValue("addr", ":8080"))
+		require.NoError(t, c.ProvideNamedValue("other", ":9090"))
+		require.NoError(t, c.Provide(func(params Params) *http.Server { return &http.Server{Addr: params.Addr} }))
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		require.Equal(t, ":8080", server.Addr)
+	})
 }
 
 func TestContainer_Resolve(t *testing.T) {
@@ -415,6 +589,74 @@ func TestContainer_Resolve(t *testing.T) {
 		require.NoError(t, c.Resolve(&server))
 		require.Equal(t, fmt.Sprintf("%p", mux), fmt.Sprintf("%p", server.Handler))
 	})
+
+	t.Run("cache stats count instance requests", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+		)
+		require.NoError(t, err)
+		require.Equal(t, di.CacheStats{}, c.CacheStats())
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		require.Equal(t, di.CacheStats{Resolves: 2, Hits: 0, Misses: 2}, c.CacheStats())
+		require.NoError(t, c.Resolve(&server))
+		require.Equal(t, di.CacheStats{Resolves: 3, Hits: 1, Misses: 2}, c.CacheStats())
+		var mux *http.ServeMux
+		require.NoError(t, c.Resolve(&mux))
+		require.Equal(t, di.CacheStats{Resolves: 4, Hits: 2, Misses: 2}, c.CacheStats())
+	})
+
+	t.Run("cache stats count invoke arguments once", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.Invoke(func(mux *http.ServeMux) {}))
+		require.Equal(t, di.CacheStats{Resolves: 1, Hits: 0, Misses: 1}, c.CacheStats())
+	})
+}
+
+func TestContainer_ResolutionOrder(t *testing.T) {
+	t.Run("dependencies go before dependents", func(t *testing.T) {
+		type Config struct{}
+		c, err := di.New(
+			di.Provide(func(config *Config, handler http.Handler) *http.Server { return &http.Server{Handler: handler} }),
+			di.Provide(func(config *Config) *http.ServeMux { return &http.ServeMux{} }, di.As(new(http.Handler))),
+			di.Provide(func() *Config { return &Config{} }),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		order, err := c.ResolutionOrder(&server)
+		require.NoError(t, err)
+		require.Equal(t, []reflect.Type{
+			reflect.TypeOf(&Config{}),
+			reflect.TypeOf(new(http.Handler)).Elem(),
+			reflect.TypeOf(&http.Server{}),
+		}, order)
+	})
+
+	t.Run("order of not existing type cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		var server *http.Server
+		_, err = c.ResolutionOrder(&server)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+		require.Contains(t, err.Error(), "container_test.go:")
+	})
+
+	t.Run("order of cyclic graph cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func(int32) bool { return true }),
+			di.Provide(func(bool) int32 { return 0 }),
+		)
+		require.NoError(t, err)
+		var b bool
+		_, err = c.ResolutionOrder(&b)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), ": cycle detected")
+	})
 }
 
 func TestContainer_Decorate(t *testing.T) {
@@ -542,6 +784,60 @@ func TestContainer_Apply(t *testing.T) {
 		require.Contains(t, err.Error(), "container_test.go:")
 		require.Contains(t, err.Error(), ": multiple definitions of io.Closer, maybe you need to use group type: []io.Closer")
 	})
+
+	t.Run("provide once", func(t *testing.T) {
+		var calls int
+		module := func() di.Option {
+			return di.ProvideOnce("server", di.Provide(func() *http.Server {
+				calls++
+				return &http.Server{}
+			}))
+		}
+		c, err := di.New(
+			module(),
+			di.Options(module()),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.Apply(module()))
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		require.Equal(t, 1, calls)
+		require.NoError(t, c.Apply(di.AllowLateRegistration(), di.ProvideOnce("mux", di.Provide(func() *http.ServeMux { return &http.ServeMux{} }))))
+		var mux *http.ServeMux
+		require.NoError(t, c.Resolve(&mux))
+	})
+
+	t.Run("provide later", func(t *testing.T) {
+		type Config struct{ Mux bool }
+		c, err := di.New(
+			di.ProvideLater(func(c *di.Container) error {
+				var config *Config
+				if err := c.Resolve(&config); err != nil {
+					return err
+				}
+				if config.Mux {
+					return c.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.As(new(http.Handler)))
+				}
+				return nil
+			}),
+			di.Provide(func() *Config { return &Config{Mux: true} }),
+			di.Invoke(func(handler http.Handler) {}),
+		)
+		require.NoError(t, err)
+		var handler http.Handler
+		require.NoError(t, c.Resolve(&handler))
+	})
+
+	t.Run("provide later error", func(t *testing.T) {
+		_, err := di.New(
+			di.ProvideLater(func(c *di.Container) error {
+				return errors.New("provide failed")
+			}),
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), ": provide failed")
+	})
 }
 
 func TestContainer_Interfaces(t *testing.T) {
@@ -597,6 +893,33 @@ func TestContainer_Interfaces(t *testing.T) {
 		require.NoError(t, c.Resolve(&handler))
 		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", handler))
 	})
+
+	t.Run("resolve pointer to interface", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		file := &os.File{}
+		require.NoError(t, c.Provide(func() *os.File { return file }, di.As(new(io.Writer))))
+		var writer *io.Writer
+		require.NoError(t, c.Resolve(&writer))
+		require.NotNil(t, writer)
+		require.Equal(t, fmt.Sprintf("%p", file), fmt.Sprintf("%p", *writer))
+		// assigning through the pointer doesn't change the binding
+		*writer = &os.File{}
+		var binding io.Writer
+		require.NoError(t, c.Resolve(&binding))
+		require.Equal(t, fmt.Sprintf("%p", file), fmt.Sprintf("%p", binding))
+	})
+
+	t.Run("resolve pointer to not provided interface cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		var writer *io.Writer
+		err = c.Resolve(&writer)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), ": type *io.Writer not exists in the container, pointer to interface is resolved from io.Writer binding")
+	})
 }
 
 func TestContainer_Groups(t *testing.T) {
@@ -650,7 +973,7 @@ func TestContainer_Groups(t *testing.T) {
 		fn1 := func() { result = append(result, "fn1") }
 		fn2 := func() { result = append(result, "fn2") }
 		fn3 := func() { result = append(result, "fn3") }
-		c, err := di.New()
+		c, err := di.New(di.AllowLateRegistration())
 		require.NoError(t, err)
 		require.NotNil(t, c)
 		type MyFunc func()
@@ -666,6 +989,25 @@ func TestContainer_Groups(t *testing.T) {
 		require.Len(t, funcs, 3)
 	})
 
+	t.Run("resolve tagged group of concrete pointer type", func(t *testing.T) {
+		type Job struct {
+			Name string
+		}
+		c, err := di.New(
+			di.Provide(func() *Job { return &Job{Name: "first"} }, di.Tags{"group": "jobs"}),
+			di.Provide(func() *Job { return &Job{Name: "untagged"} }),
+			di.Provide(func() *Job { return &Job{Name: "second"} }, di.Tags{"group": "jobs"}),
+			di.Provide(func() *Job { return &Job{Name: "third"} }, di.Tags{"group": "jobs"}),
+		)
+		require.NoError(t, err)
+		var jobs []*Job
+		require.NoError(t, c.Resolve(&jobs, di.Tags{"group": "jobs"}))
+		require.Len(t, jobs, 3)
+		require.Equal(t, "first", jobs[0].Name)
+		require.Equal(t, "second", jobs[1].Name)
+		require.Equal(t, "third", jobs[2].Name)
+	})
+
 	t.Run("resolve one interface from group of type", func(t *testing.T) {
 		c, err := di.New()
 		require.NoError(t, err)
@@ -678,6 +1020,142 @@ func TestContainer_Groups(t *testing.T) {
 		require.NoError(t, c.Resolve(&conn))
 		require.Equal(t, fmt.Sprintf("%p", conn), fmt.Sprintf("%p", conn))
 	})
+
+	t.Run("group members included by condition", func(t *testing.T) {
+		evaluated := 0
+		enabled := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return enabled }, di.As(new(io.Closer)), di.GroupMemberIf(func() bool {
+				evaluated++
+				return true
+			})),
+			di.Provide(func() *os.File { return &os.File{} }, di.As(new(io.Closer)), di.GroupMemberIf(func() bool {
+				evaluated++
+				return false
+			})),
+		)
+		require.NoError(t, err)
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers))
+		require.Len(t, closers, 1)
+		require.Equal(t, fmt.Sprintf("%p", enabled), fmt.Sprintf("%p", closers[0]))
+		require.NoError(t, c.Resolve(&closers))
+		require.Equal(t, 2, evaluated)
+		// excluded member can be resolved directly
+		var file *os.File
+		require.NoError(t, c.Resolve(&file))
+	})
+
+	t.Run("group without members met condition cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.GroupMemberIf(func() bool { return false })),
+		)
+		require.NoError(t, err)
+		var servers []*http.Server
+		err = c.Resolve(&servers)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+	})
+
+	t.Run("group size without building members", func(t *testing.T) {
+		built := false
+		c, err := di.New(
+			di.Provide(func() *http.Server {
+				built = true
+				return &http.Server{}
+			}, di.As(new(io.Closer)), di.Tags{"type": "server"}),
+			di.Provide(func() *os.File { return &os.File{} }, di.As(new(io.Closer))),
+			di.Provide(func() *net.TCPConn { return &net.TCPConn{} }, di.As(new(io.Closer)), di.GroupMemberIf(func() bool { return false })),
+		)
+		require.NoError(t, err)
+		size, err := di.GroupSize[io.Closer](c)
+		require.NoError(t, err)
+		require.Equal(t, 2, size)
+		size, err = di.GroupSize[io.Closer](c, di.Tags{"type": "server"})
+		require.NoError(t, err)
+		require.Equal(t, 1, size)
+		size, err = di.GroupSize[io.Reader](c)
+		require.NoError(t, err)
+		require.Zero(t, size)
+		require.False(t, built)
+	})
+
+	t.Run("group size matches resolve options", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.As(new(io.Closer)), di.Tags{"disabled": "true"}),
+			di.Provide(func() *os.File { return &os.File{} }, di.As(new(io.Closer))),
+			di.Provide(func() *net.TCPConn { return nil }, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		size, err := di.GroupSize[io.Closer](c, di.Not(di.Tags{"disabled": "true"}))
+		require.NoError(t, err)
+		require.Equal(t, 2, size)
+		size, err = di.GroupSize[io.Closer](c, di.SkipNilMembers())
+		require.NoError(t, err)
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers, di.SkipNilMembers()))
+		require.Equal(t, len(closers), size)
+	})
+
+	t.Run("group size of member with missing dependency cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		_, err = di.GroupSize[io.Closer](c)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "type *http.ServeMux not exists in the container")
+	})
+
+	t.Run("group defaults merge tags into members", func(t *testing.T) {
+		c, err := di.New(
+			di.GroupDefaults(di.Tags{"layer": "http", "env": "prod"},
+				di.Provide(func() *http.Server { return &http.Server{} }, di.As(new(io.Closer))),
+				di.Provide(func() *os.File { return &os.File{} }, di.As(new(io.Closer)), di.Tags{"env": "test"}),
+			),
+			di.Provide(func() *net.TCPConn { return &net.TCPConn{} }, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers, di.Tags{"layer": "http"}))
+		require.Len(t, closers, 2)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server, di.Tags{"layer": "http", "env": "prod"}))
+		var file *os.File
+		require.NoError(t, c.Resolve(&file, di.Tags{"layer": "http", "env": "test"}))
+		require.Error(t, c.Resolve(&file, di.Tags{"env": "prod"}))
+	})
+
+	t.Run("exclude members by tags", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.As(new(io.Closer))),
+			di.Provide(func() *os.File { return &os.File{} }, di.As(new(io.Closer)), di.Tags{"disabled": "true"}),
+			di.Provide(func() *net.TCPConn { return &net.TCPConn{} }, di.As(new(io.Closer)), di.Tags{"disabled": "false", "legacy": "yes"}),
+		)
+		require.NoError(t, err)
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers, di.Not(di.Tags{"disabled": "true"})))
+		require.Len(t, closers, 2)
+		require.IsType(t, &http.Server{}, closers[0])
+		require.IsType(t, &net.TCPConn{}, closers[1])
+		require.NoError(t, c.Resolve(&closers, di.Not(di.Tags{"disabled": "*"})))
+		require.Len(t, closers, 1)
+		require.NoError(t, c.Resolve(&closers, di.Not(di.Tags{"disabled": "true"}), di.Not(di.Tags{"legacy": "yes"})))
+		require.Len(t, closers, 1)
+		require.NoError(t, c.Resolve(&closers, di.Not(di.Tags{"disabled": "*"}), di.Not(di.Tags{})))
+		require.Error(t, c.Resolve(&closers, di.Not(di.Tags{"disabled": "*"}), di.Tags{"disabled": "true"}))
+	})
+
+	t.Run("exclude members from not slice cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		err = c.Resolve(&server, di.Not(di.Tags{"disabled": "true"}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "members can be excluded from slice, got *http.Server")
+	})
 }
 
 func TestContainer_Iterate(t *testing.T) {
@@ -813,7 +1291,7 @@ func TestContainer_Tags(t *testing.T) {
 	})
 
 	t.Run("resolve single instance of group without specifying tags cause error", func(t *testing.T) {
-		c, err := di.New()
+		c, err := di.New(di.AllowLateRegistration())
 		require.NoError(t, err)
 		require.NotNil(t, c)
 		require.NoError(t, c.Provide(http.NewServeMux, di.WithName("first")))
@@ -868,7 +1346,7 @@ func TestContainer_Tags(t *testing.T) {
 		require.NoError(t, err)
 		require.NoError(t, c.Provide(http.NewServeMux))
 		require.NoError(t, c.Provide(http.NewServeMux, di.Tags{"tag": "the_same"}))
-		require.NoError(t, c.Provide(http.NewServeMux, di.Tags{"tag": "the_same"}))
+		require.NoError(t, c.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.Tags{"tag": "the_same"}))
 		var muxs []*http.ServeMux
 		err = c.Resolve(&muxs, di.Tags{"tag": "the_same"})
 		require.NoError(t, err)
@@ -965,20 +1443,244 @@ func TestContainer_Group(t *testing.T) {
 		require.Contains(t, err.Error(), "container_test.go:")
 		require.Contains(t, err.Error(), ": invalid invocation signature, got func() *http.Server")
 	})
-}
 
-func TestContainer_Invoke(t *testing.T) {
-	t.Run("invoke nil", func(t *testing.T) {
-		c, err := di.New()
+	t.Run("provide member of interface group", func(t *testing.T) {
+		server := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return server }, di.InGroup(new([]io.Closer))),
+		)
 		require.NoError(t, err)
-		err = c.Invoke(nil)
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "container_test.go:")
-		require.Contains(t, err.Error(), ": invalid invocation signature, got nil")
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers))
+		require.Len(t, closers, 1)
+		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", closers[0]))
 	})
 
-	t.Run("invoke non function type", func(t *testing.T) {
-		c, err := di.New()
+	t.Run("provide member of type group", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.InGroup(new([]*http.Server))),
+		)
+		require.NoError(t, err)
+		var servers []*http.Server
+		require.NoError(t, c.Resolve(&servers))
+		require.Len(t, servers, 1)
+	})
+
+	t.Run("provide member that not implement group interface cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		err = c.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.InGroup(new([]io.Closer)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), ": *http.ServeMux not implement io.Closer, can't be a member of group []io.Closer")
+	})
+
+	t.Run("provide member of another type group cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		err = c.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.InGroup(new([]*http.Server)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), ": *http.ServeMux can't be a member of group []*http.Server")
+	})
+
+	t.Run("provide member of invalid group cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		err = c.Provide(func() *http.Server { return &http.Server{} }, di.InGroup(new(io.Closer)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), ": invalid group, must be a pointer to slice, got *io.Closer")
+	})
+
+	t.Run("resolve concatenated groups in order", func(t *testing.T) {
+		type Middleware interface{}
+		c, err := di.New(
+			di.ProvideValue("post", di.As(new(Middleware)), di.Tags{"phase": "post"}),
+			di.ProvideValue(1, di.As(new(Middleware)), di.Tags{"phase": "pre"}),
+			di.ProvideValue(true, di.As(new(Middleware)), di.Tags{"phase": "other"}),
+			di.ProvideValue(2.5, di.As(new(Middleware)), di.Tags{"phase": "pre"}),
+		)
+		require.NoError(t, err)
+		var middlewares []Middleware
+		require.NoError(t, c.Resolve(&middlewares, di.ConcatGroups(di.Tags{"phase": "pre"}, di.Tags{"phase": "post"})))
+		require.Equal(t, []Middleware{1, 2.5, "post"}, middlewares)
+	})
+
+	t.Run("resolve concatenated groups without members cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.Tags{"phase": "pre"}),
+		)
+		require.NoError(t, err)
+		var servers []*http.Server
+		err = c.Resolve(&servers, di.ConcatGroups(di.Tags{"phase": "post"}))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+	})
+
+	t.Run("concatenate groups into not a slice cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		var server *http.Server
+		err = c.Resolve(&server, di.ConcatGroups(di.Tags{"phase": "post"}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "groups can be concatenated into slice, got *http.Server")
+	})
+
+	t.Run("provide group of constructors", func(t *testing.T) {
+		server := &http.Server{}
+		file := &os.File{}
+		conn := &net.TCPConn{}
+		c, err := di.New(
+			di.ProvideGroup[io.Closer](
+				func() *http.Server { return server },
+				func() *os.File { return file },
+			),
+			di.Provide(func() *net.TCPConn { return conn }, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers))
+		require.Len(t, closers, 3)
+		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", closers[0]))
+		require.Equal(t, fmt.Sprintf("%p", file), fmt.Sprintf("%p", closers[1]))
+		require.Equal(t, fmt.Sprintf("%p", conn), fmt.Sprintf("%p", closers[2]))
+	})
+
+	t.Run("provide group of constructor that not implement interface cause error", func(t *testing.T) {
+		_, err := di.New(
+			di.ProvideGroup[io.Closer](
+				func() *http.Server { return &http.Server{} },
+				func() *http.ServeMux { return &http.ServeMux{} },
+			),
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), ": *http.ServeMux not implement io.Closer, can't be a member of group []io.Closer")
+	})
+
+	t.Run("resolve group deduplicated by tag", func(t *testing.T) {
+		type Plugin interface{}
+		c, err := di.New(
+			di.ProvideValue(1, di.As(new(Plugin)), di.Tags{"name": "metrics"}),
+			di.ProvideValue("untagged", di.As(new(Plugin))),
+			di.ProvideValue(2, di.As(new(Plugin)), di.Tags{"name": "tracing"}),
+			di.ProvideValue(3, di.As(new(Plugin)), di.Tags{"name": "metrics"}),
+		)
+		require.NoError(t, err)
+		var plugins []Plugin
+		require.NoError(t, c.Resolve(&plugins, di.DedupeFirst("name")))
+		require.Equal(t, []Plugin{1, "untagged", 2}, plugins)
+		plugins = nil
+		require.NoError(t, c.Resolve(&plugins, di.DedupeLast("name")))
+		require.Equal(t, []Plugin{3, "untagged", 2}, plugins)
+		plugins = nil
+		require.NoError(t, c.Resolve(&plugins))
+		require.Equal(t, []Plugin{1, "untagged", 2, 3}, plugins)
+	})
+
+	t.Run("deduplicate not a slice cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		var server *http.Server
+		err = c.Resolve(&server, di.DedupeFirst("name"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "group can be deduplicated in slice, got *http.Server")
+	})
+
+	t.Run("resolve group that is provided directly cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.As(new(io.Closer))),
+			di.Provide(func() []io.Closer { return nil }),
+		)
+		require.NoError(t, err)
+		var closers []io.Closer
+		err = c.Resolve(&closers)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "type []io.Closer is provided directly and is a group of provided io.Closer, provide either the slice or its elements")
+	})
+
+	t.Run("resolve slice that is provided directly with tagged elements", func(t *testing.T) {
+		c, err := di.New(
+			di.ProvideValue([]string{"a"}),
+			di.ProvideValue("x", di.WithName("addr")),
+		)
+		require.NoError(t, err)
+		var list []string
+		require.NoError(t, c.Resolve(&list))
+		require.Equal(t, []string{"a"}, list)
+		require.NoError(t, c.Resolve(&list, di.Name("addr")))
+		require.Equal(t, []string{"x"}, list)
+	})
+
+	t.Run("resolve slice that is provided directly", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() []io.Closer { return []io.Closer{&os.File{}} }),
+		)
+		require.NoError(t, err)
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers))
+		require.Len(t, closers, 1)
+	})
+
+	t.Run("resolve empty group", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.Tags{"name": "public"}),
+		)
+		require.NoError(t, err)
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers, di.AllowEmptyGroup()))
+		require.NotNil(t, closers)
+		require.Empty(t, closers)
+		var servers []*http.Server
+		require.NoError(t, c.Resolve(&servers, di.Name("private"), di.AllowEmptyGroup()))
+		require.NotNil(t, servers)
+		require.Empty(t, servers)
+		err = c.Resolve(&servers, di.Name("private"))
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+	})
+
+	t.Run("resolve group without nil members", func(t *testing.T) {
+		server := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return nil }, di.As(new(http.Handler))),
+			di.Provide(func() *httpHandler { return &httpHandler{} }, di.As(new(http.Handler))),
+			di.Provide(func() *http.Server { return server }, di.As(new(io.Closer))),
+			di.Provide(func() *os.File { return nil }, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		var handlers []http.Handler
+		require.NoError(t, c.Resolve(&handlers))
+		require.Len(t, handlers, 2)
+		handlers = nil
+		require.NoError(t, c.Resolve(&handlers, di.SkipNilMembers()))
+		require.Len(t, handlers, 1)
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers, di.SkipNilMembers()))
+		require.Len(t, closers, 1)
+		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", closers[0]))
+	})
+
+	t.Run("skip nil members of not a slice cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		var server *http.Server
+		err = c.Resolve(&server, di.SkipNilMembers())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "nil members can be skipped in slice, got *http.Server")
+	})
+}
+
+func TestContainer_Invoke(t *testing.T) {
+	t.Run("invoke nil", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		err = c.Invoke(nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), ": invalid invocation signature, got nil")
+	})
+
+	t.Run("invoke non function type", func(t *testing.T) {
+		c, err := di.New()
 		require.NoError(t, err)
 		err = c.Invoke(1)
 		require.Error(t, err)
@@ -1061,6 +1763,66 @@ func TestContainer_Invoke(t *testing.T) {
 		require.Contains(t, err.Error(), "container_test.go:")
 		require.Contains(t, err.Error(), ": cycle detected") // todo: improve message
 	})
+
+	t.Run("invoke with context", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		var invoked bool
+		require.NoError(t, c.InvokeContext(context.Background(), func(server *http.Server) { invoked = true }))
+		require.True(t, invoked)
+	})
+
+	t.Run("invoke with cancelled context aborts build", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var built bool
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux {
+				cancel()
+				return &http.ServeMux{}
+			}),
+			di.Provide(func(mux *http.ServeMux) *http.Server {
+				built = true
+				return &http.Server{Handler: mux}
+			}),
+		)
+		require.NoError(t, err)
+		err = c.InvokeContext(ctx, func(server *http.Server) {
+			require.Fail(t, "invocation must not be called")
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.False(t, built)
+	})
+
+	t.Run("invoke with bound values", func(t *testing.T) {
+		server := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return server }),
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+		)
+		require.NoError(t, err)
+		req := &http.Request{}
+		mux := &http.ServeMux{}
+		err = c.Invoke(func(r *http.Request, s *http.Server, m *http.ServeMux, h http.Handler) {
+			require.Equal(t, fmt.Sprintf("%p", req), fmt.Sprintf("%p", r))
+			require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", s))
+			require.Equal(t, fmt.Sprintf("%p", mux), fmt.Sprintf("%p", m))
+			require.Equal(t, fmt.Sprintf("%p", mux), fmt.Sprintf("%p", h))
+		}, di.With(req), di.With(mux))
+		require.NoError(t, err)
+		var req2 *http.Request
+		require.Error(t, c.Resolve(&req2))
+	})
+
+	t.Run("invoke with nil bound value cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		err = c.Invoke(func() {}, di.With(nil))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid invocation value, got nil")
+	})
 }
 
 func TestContainer_Has(t *testing.T) {
@@ -1523,6 +2285,95 @@ func TestContainer_Inject(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, fmt.Sprintf("%p", mux), fmt.Sprintf("%p", ip.Mux))
 	})
+
+	t.Run("resolved injectable not registered as type", func(t *testing.T) {
+		type Parameters struct {
+			di.Inject
+			Server *http.Server
+		}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		var params *Parameters
+		require.NoError(t, c.Resolve(&params))
+		require.Equal(t, []reflect.Type{reflect.TypeOf(new(di.Container)), reflect.TypeOf(new(http.Server))}, c.Types())
+		var again *Parameters
+		require.NoError(t, c.Resolve(&again))
+		require.Equal(t, fmt.Sprintf("%p", params), fmt.Sprintf("%p", again))
+	})
+
+	t.Run("resolve injectable without cache", func(t *testing.T) {
+		type Parameters struct {
+			di.Inject
+			Server *http.Server
+		}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+			di.NoInjectCache(),
+		)
+		require.NoError(t, err)
+		var params *Parameters
+		require.NoError(t, c.Resolve(&params))
+		var again *Parameters
+		require.NoError(t, c.Resolve(&again))
+		require.NotEqual(t, fmt.Sprintf("%p", params), fmt.Sprintf("%p", again))
+		require.Equal(t, fmt.Sprintf("%p", params.Server), fmt.Sprintf("%p", again.Server))
+	})
+
+	t.Run("unexported field with tag cause error", func(t *testing.T) {
+		type Parameters struct {
+			di.Inject
+			Server *http.Server
+			mux    *http.ServeMux `di:""`
+			file   *os.File
+		}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+		)
+		require.NoError(t, err)
+		var params *Parameters
+		err = c.Resolve(&params)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cannot inject unexported field mux of di_test.Parameters")
+	})
+
+	t.Run("unexported field with deprecated tag cause error", func(t *testing.T) {
+		type Parameters struct {
+			di.Inject
+			Server *http.Server
+			mux    *http.ServeMux `type:"public"`
+		}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		var params *Parameters
+		err = c.Resolve(&params)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cannot inject unexported field mux of di_test.Parameters")
+	})
+
+	t.Run("unexported fields without tag or with skip tag are skipped", func(t *testing.T) {
+		type Parameters struct {
+			di.Inject
+			Server *http.Server
+			mux    *http.ServeMux
+			file   *os.File     `di:"skip"`
+			conn   *net.TCPConn `skip:"true"`
+		}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		var params *Parameters
+		require.NoError(t, c.Resolve(&params))
+		require.NotNil(t, params.Server)
+		require.Nil(t, params.mux)
+		require.Nil(t, params.file)
+		require.Nil(t, params.conn)
+	})
 }
 
 func TestContainer_Cleanup(t *testing.T) {
@@ -1554,6 +2405,168 @@ func TestContainer_Cleanup(t *testing.T) {
 		c.Cleanup()
 		require.Equal(t, []string{"server", "mux"}, cleanupCalls)
 	})
+
+	t.Run("cleanup errors are joined", func(t *testing.T) {
+		var cleanupCalls []string
+		c, err := di.New(
+			di.Provide(func(mux *http.ServeMux) (*http.Server, func() error) {
+				return &http.Server{Handler: mux}, func() error {
+					cleanupCalls = append(cleanupCalls, "server")
+					return errors.New("server close failed")
+				}
+			}),
+			di.Provide(func() (*http.ServeMux, func() error, error) {
+				return &http.ServeMux{}, func() error {
+					cleanupCalls = append(cleanupCalls, "mux")
+					return errors.New("mux close failed")
+				}, nil
+			}),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		err = c.Cleanup()
+		require.Error(t, err)
+		require.Equal(t, "*http.Server cleanup: server close failed\n*http.ServeMux cleanup: mux close failed", err.Error())
+		require.Equal(t, []string{"server", "mux"}, cleanupCalls)
+	})
+
+	t.Run("stop on error", func(t *testing.T) {
+		var cleanupCalls []string
+		closeErr := errors.New("server close failed")
+		c, err := di.New(
+			di.Provide(func(mux *http.ServeMux) (*http.Server, func() error) {
+				return &http.Server{Handler: mux}, func() error {
+					cleanupCalls = append(cleanupCalls, "server")
+					return closeErr
+				}
+			}),
+			di.Provide(func() (*http.ServeMux, func()) {
+				return &http.ServeMux{}, func() { cleanupCalls = append(cleanupCalls, "mux") }
+			}),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		err = c.Cleanup(di.StopOnError())
+		require.True(t, errors.Is(err, closeErr))
+		require.Equal(t, []string{"server"}, cleanupCalls)
+	})
+
+	t.Run("nil cleanup is skipped", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() (*http.Server, func()) { return &http.Server{}, nil }),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		require.NoError(t, c.Cleanup())
+	})
+
+	t.Run("skip slow cleanups", func(t *testing.T) {
+		var cleanupCalls []string
+		release := make(chan struct{})
+		defer close(release)
+		c, err := di.New(
+			di.Provide(func(mux *http.ServeMux) (*http.Server, func()) {
+				return &http.Server{Handler: mux}, func() { <-release }
+			}),
+			di.Provide(func() (*http.ServeMux, func()) {
+				return &http.ServeMux{}, func() { cleanupCalls = append(cleanupCalls, "mux") }
+			}),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		err = c.Cleanup(di.SkipSlow(10 * time.Millisecond))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrCleanupSkipped))
+		require.Equal(t, "*http.Server cleanup skipped: exceeded 10ms", err.Error())
+		require.Equal(t, []string{"mux"}, cleanupCalls)
+	})
+
+	t.Run("skip slow returns cleanup error", func(t *testing.T) {
+		closeErr := errors.New("server close failed")
+		c, err := di.New(
+			di.Provide(func() (*http.Server, func() error) {
+				return &http.Server{}, func() error { return closeErr }
+			}),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		err = c.Cleanup(di.SkipSlow(time.Second))
+		require.True(t, errors.Is(err, closeErr))
+		require.False(t, errors.Is(err, di.ErrCleanupSkipped))
+	})
+
+	t.Run("cleanup resolves built instances", func(t *testing.T) {
+		var logs []string
+		c, err := di.New(
+			di.Provide(func() (*bytes.Buffer, func()) {
+				return &bytes.Buffer{}, func() { logs = append(logs, "buffer closed") }
+			}),
+			di.Provide(func(buf *bytes.Buffer) (*http.Server, func(r di.CleanupResolver) error) {
+				return &http.Server{}, func(r di.CleanupResolver) error {
+					var buffer *bytes.Buffer
+					if err := r.Resolve(&buffer); err != nil {
+						return err
+					}
+					logs = append(logs, "server closed")
+					return nil
+				}
+			}),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		require.NoError(t, c.Cleanup())
+		require.Equal(t, []string{"server closed", "buffer closed"}, logs)
+	})
+
+	t.Run("cleanup resolves cleaned up instance cause error", func(t *testing.T) {
+		var resolveErr error
+		c, err := di.New(
+			di.Provide(func() (*bytes.Buffer, func(r di.CleanupResolver)) {
+				return &bytes.Buffer{}, func(r di.CleanupResolver) {
+					var server *http.Server
+					resolveErr = r.Resolve(&server)
+				}
+			}),
+			di.Provide(func(buf *bytes.Buffer) (*http.Server, func()) {
+				return &http.Server{}, func() {}
+			}),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		require.NoError(t, c.Cleanup())
+		require.Error(t, resolveErr)
+		require.Contains(t, resolveErr.Error(), "*http.Server is already cleaned up")
+	})
+
+	t.Run("cleanup resolves not built instance cause error", func(t *testing.T) {
+		var calls int
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux {
+				calls++
+				return &http.ServeMux{}
+			}),
+			di.Provide(func() (*http.Server, func(r di.CleanupResolver) error) {
+				return &http.Server{}, func(r di.CleanupResolver) error {
+					var mux *http.ServeMux
+					return r.Resolve(&mux)
+				}
+			}),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		err = c.Cleanup()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*http.ServeMux is not built, cleanup can't build instances")
+		require.Equal(t, 0, calls)
+	})
 }
 
 func TestContainer_AddParent(t *testing.T) {
@@ -1632,3 +2645,2135 @@ func TestContainer_AddParent(t *testing.T) {
 	})
 
 }
+
+func TestResolveImplementations(t *testing.T) {
+	t.Run("resolve all implementations of interface", func(t *testing.T) {
+		server := &http.Server{}
+		file := &os.File{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return server }, di.As(new(io.Closer))),
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+			di.Provide(func() *os.File { return file }),
+		)
+		require.NoError(t, err)
+		closers, err := di.ResolveImplementations[[]io.Closer](c)
+		require.NoError(t, err)
+		require.Len(t, closers, 2)
+		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", closers[0]))
+		require.Equal(t, fmt.Sprintf("%p", file), fmt.Sprintf("%p", closers[1]))
+	})
+
+	t.Run("resolve implementations with tags", func(t *testing.T) {
+		file := &os.File{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+			di.Provide(func() *os.File { return file }, di.Tags{"type": "file"}),
+		)
+		require.NoError(t, err)
+		closers, err := di.ResolveImplementations[[]io.Closer](c, di.Tags{"type": "file"})
+		require.NoError(t, err)
+		require.Len(t, closers, 1)
+		require.Equal(t, fmt.Sprintf("%p", file), fmt.Sprintf("%p", closers[0]))
+	})
+
+	t.Run("resolve implementations of not implemented interface cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		_, err = di.ResolveImplementations[[]io.Closer](c)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), ": type []io.Closer not exists in the container")
+	})
+
+	t.Run("resolve implementations into not slice of interfaces cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		_, err = di.ResolveImplementations[[]*http.Server](c)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), ": implementations can be resolved into slice of interfaces, got []*http.Server")
+	})
+}
+
+func TestContainer_Build(t *testing.T) {
+	t.Run("eager types built on container creation", func(t *testing.T) {
+		var built []string
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux {
+				built = append(built, "mux")
+				return &http.ServeMux{}
+			}),
+			di.Provide(func(mux *http.ServeMux) *http.Server {
+				built = append(built, "server")
+				return &http.Server{Handler: mux}
+			}, di.Eager()),
+		)
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		require.Equal(t, []string{"mux", "server"}, built)
+	})
+
+	t.Run("shared dependency of eager types built once", func(t *testing.T) {
+		type First struct{ Mux *http.ServeMux }
+		type Second struct{ Mux *http.ServeMux }
+		calls := 0
+		var first *First
+		var second *Second
+		c, err := di.New(
+			di.Provide(func(mux *http.ServeMux) *First { return &First{Mux: mux} }, di.Eager()),
+			di.Provide(func(mux *http.ServeMux) *Second { return &Second{Mux: mux} }, di.Eager()),
+			di.Provide(func() *http.ServeMux {
+				calls++
+				return &http.ServeMux{}
+			}),
+		)
+		require.NoError(t, err)
+		require.Equal(t, 1, calls)
+		require.NoError(t, c.Resolve(&first))
+		require.NoError(t, c.Resolve(&second))
+		require.Equal(t, 1, calls)
+		require.Equal(t, fmt.Sprintf("%p", first.Mux), fmt.Sprintf("%p", second.Mux))
+	})
+
+	t.Run("build eager types provided after creation", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		built := false
+		require.NoError(t, c.Provide(func() *http.Server {
+			built = true
+			return &http.Server{}
+		}, di.Eager()))
+		require.False(t, built)
+		require.NoError(t, c.Build())
+		require.True(t, built)
+	})
+
+	t.Run("eager type build error", func(t *testing.T) {
+		_, err := di.New(
+			di.Provide(func() (*http.Server, error) {
+				return nil, fmt.Errorf("server build failed")
+			}, di.Eager()),
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*http.Server: server build failed")
+	})
+}
+
+func TestContainer_ProvideStruct(t *testing.T) {
+	t.Run("provide module constructors with tags", func(t *testing.T) {
+		type Module struct {
+			Primary func() *http.Server `di:"name=primary"`
+			Replica func() *http.Server `di:"name=replica"`
+			Mux     func() *http.ServeMux
+			Skipped func() *os.File `di:"skip"`
+			Nil     func() *net.TCPConn
+		}
+		primary := &http.Server{}
+		replica := &http.Server{}
+		c, err := di.New(
+			di.ProvideStruct(Module{
+				Primary: func() *http.Server { return primary },
+				Replica: func() *http.Server { return replica },
+				Mux:     http.NewServeMux,
+				Skipped: func() *os.File { return &os.File{} },
+			}),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server, di.Name("primary")))
+		require.Equal(t, fmt.Sprintf("%p", primary), fmt.Sprintf("%p", server))
+		require.NoError(t, c.Resolve(&server, di.Name("replica")))
+		require.Equal(t, fmt.Sprintf("%p", replica), fmt.Sprintf("%p", server))
+		var mux *http.ServeMux
+		require.NoError(t, c.Resolve(&mux))
+		has, err := c.Has(new(*os.File))
+		require.NoError(t, err)
+		require.False(t, has)
+		has, err = c.Has(new(*net.TCPConn))
+		require.NoError(t, err)
+		require.False(t, has)
+	})
+
+	t.Run("provide not struct module cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		err = c.ProvideStruct("module")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), ": invalid module, got string")
+	})
+
+	t.Run("provide module with invalid constructor cause error", func(t *testing.T) {
+		type Module struct {
+			Server *http.Server
+		}
+		_, err := di.New(
+			di.ProvideStruct(&Module{}),
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), ": di_test.Module.Server: invalid constructor signature, got *http.Server: constructor must be a function")
+	})
+}
+
+func TestContainer_Warm(t *testing.T) {
+	t.Run("warm builds independent dependencies concurrently", func(t *testing.T) {
+		type First struct{}
+		type Second struct{}
+		type Shared struct{}
+		var started sync.WaitGroup
+		started.Add(2)
+		var sharedCalls int32
+		c, err := di.New(
+			di.Provide(func() *Shared {
+				atomic.AddInt32(&sharedCalls, 1)
+				return &Shared{}
+			}),
+			di.Provide(func(*Shared) *First {
+				// waits second constructor, deadlocks without concurrent build
+				started.Done()
+				started.Wait()
+				return &First{}
+			}),
+			di.Provide(func(*Shared) *Second {
+				started.Done()
+				started.Wait()
+				return &Second{}
+			}),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.Warm(new(*First), new(*Second)))
+		require.Equal(t, int32(1), atomic.LoadInt32(&sharedCalls))
+		var first *First
+		require.NoError(t, c.Resolve(&first))
+		require.Equal(t, di.CacheStats{Resolves: 6, Hits: 3, Misses: 3}, c.CacheStats())
+	})
+
+	t.Run("warm returns build error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() (*http.ServeMux, error) { return nil, errors.New("mux build failed") }),
+			di.Provide(func(*http.ServeMux) *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		err = c.Warm(new(*http.Server))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), ": *http.ServeMux: mux build failed")
+	})
+
+	t.Run("failed shared dependency is built once", func(t *testing.T) {
+		var calls int32
+		c, err := di.New(
+			di.Provide(func() (*http.ServeMux, error) {
+				atomic.AddInt32(&calls, 1)
+				return nil, errors.New("mux build failed")
+			}),
+			di.Provide(func(*http.ServeMux) *http.Server { return &http.Server{} }),
+			di.Provide(func(*http.ServeMux) *httpHandler { return &httpHandler{} }),
+		)
+		require.NoError(t, err)
+		err = c.Warm(new(*http.Server), new(*httpHandler))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), ": *http.ServeMux: mux build failed")
+		require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("warm not existing type cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		err = c.Warm(new(*http.Server))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+	})
+}
+
+func TestContainer_Invalidate(t *testing.T) {
+	type Config struct {
+		Version int
+	}
+	type Server struct {
+		Config *Config
+	}
+	type Handler struct {
+		Config *Config
+	}
+	type Router struct {
+		Handlers []*Handler
+	}
+	t.Run("invalidate rebuilds volatile type and dependents", func(t *testing.T) {
+		version := 0
+		c, err := di.New(
+			di.Provide(func() *Config {
+				version++
+				return &Config{Version: version}
+			}, di.Volatile()),
+			di.Provide(func(config *Config) *Server { return &Server{Config: config} }),
+			di.Provide(func(config *Config) *Handler { return &Handler{Config: config} }),
+			di.Provide(func(handlers []*Handler) *Router { return &Router{Handlers: handlers} }),
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+		)
+		require.NoError(t, err)
+		var server *Server
+		var router *Router
+		var mux *http.ServeMux
+		require.NoError(t, c.Resolve(&server))
+		require.NoError(t, c.Resolve(&router))
+		require.NoError(t, c.Resolve(&mux))
+		require.Equal(t, 1, server.Config.Version)
+		require.Equal(t, 1, router.Handlers[0].Config.Version)
+		require.NoError(t, c.Invalidate(reflect.TypeOf(&Config{})))
+		var reloaded *Server
+		var reloadedRouter *Router
+		var sameMux *http.ServeMux
+		require.NoError(t, c.Resolve(&reloaded))
+		require.NoError(t, c.Resolve(&reloadedRouter))
+		require.NoError(t, c.Resolve(&sameMux))
+		require.Equal(t, 2, reloaded.Config.Version)
+		require.Equal(t, 2, reloadedRouter.Handlers[0].Config.Version)
+		require.NotEqual(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", reloaded))
+		require.Equal(t, fmt.Sprintf("%p", mux), fmt.Sprintf("%p", sameMux))
+	})
+
+	t.Run("invalidate not volatile type cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *Config { return &Config{} }),
+		)
+		require.NoError(t, err)
+		err = c.Invalidate(reflect.TypeOf(&Config{}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), ": type *di_test.Config is not volatile, use di.Volatile() provide option")
+	})
+
+	t.Run("invalidate rebuilds dependents of not cached di.Inject struct", func(t *testing.T) {
+		type Params struct {
+			di.Inject
+			Config *Config
+		}
+		version := 0
+		c, err := di.New(
+			di.NoInjectCache(),
+			di.Provide(func() *Config {
+				version++
+				return &Config{Version: version}
+			}, di.Volatile()),
+			di.Provide(func(params Params) *Server { return &Server{Config: params.Config} }),
+		)
+		require.NoError(t, err)
+		var server *Server
+		require.NoError(t, c.Resolve(&server))
+		require.Equal(t, 1, server.Config.Version)
+		require.NoError(t, c.Invalidate(reflect.TypeOf(&Config{})))
+		require.NoError(t, c.Resolve(&server))
+		require.Equal(t, 2, server.Config.Version)
+	})
+}
+
+func TestContainer_Factory(t *testing.T) {
+	t.Run("inject factory of type", func(t *testing.T) {
+		type Pool struct {
+			New func() *http.Server
+		}
+		server := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return server }),
+			di.Provide(func(factory func() *http.Server) *Pool { return &Pool{New: factory} }),
+		)
+		require.NoError(t, err)
+		var pool *Pool
+		require.NoError(t, c.Resolve(&pool))
+		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", pool.New()))
+	})
+
+	t.Run("inject factory with error", func(t *testing.T) {
+		calls := 0
+		c, err := di.New(
+			di.Provide(func() (*http.Server, error) {
+				calls++
+				return nil, errors.New("server build failed")
+			}),
+		)
+		require.NoError(t, err)
+		err = c.Invoke(func(factory func() (*http.Server, error)) {
+			require.Zero(t, calls)
+			server, err := factory()
+			require.Error(t, err)
+			require.Nil(t, server)
+			require.Contains(t, err.Error(), "server build failed")
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("resolve factory with tags", func(t *testing.T) {
+		first := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return first }, di.Tags{"name": "first"}),
+			di.Provide(func() *http.Server { return &http.Server{} }, di.Tags{"name": "second"}),
+		)
+		require.NoError(t, err)
+		var factory func() *http.Server
+		require.NoError(t, c.Resolve(&factory, di.Name("first")))
+		require.Equal(t, fmt.Sprintf("%p", first), fmt.Sprintf("%p", factory()))
+	})
+
+	t.Run("factory of not existing type cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		var factory func() (*http.Server, error)
+		err = c.Resolve(&factory)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+		require.Contains(t, err.Error(), ": factory func() (*http.Server, error): type *http.Server not exists in the container")
+	})
+
+	t.Run("provided function type takes precedence over factory", func(t *testing.T) {
+		type Factory func() *http.Server
+		server := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() Factory { return func() *http.Server { return server } }),
+		)
+		require.NoError(t, err)
+		var factory Factory
+		require.NoError(t, c.Resolve(&factory))
+		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", factory()))
+	})
+
+	t.Run("factory called on construction of its dependency cause cycle error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func(factory func() (*http.ServeMux, error)) (*http.Server, error) {
+				mux, err := factory()
+				if err != nil {
+					return nil, err
+				}
+				return &http.Server{Handler: mux}, nil
+			}),
+			di.Provide(func(server *http.Server) *http.ServeMux { return &http.ServeMux{} }),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		err = c.Resolve(&server)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*http.Server is under construction: cycle detected")
+	})
+
+	t.Run("factory of alias called on construction of its node cause cycle error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func(factory func() (io.Closer, error)) (*os.File, error) {
+				if _, err := factory(); err != nil {
+					return nil, err
+				}
+				return &os.File{}, nil
+			}, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		var file *os.File
+		err = c.Resolve(&file)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "io.Closer is under construction: cycle detected")
+	})
+
+	t.Run("factory called after construction resolves dependent", func(t *testing.T) {
+		type Lazy struct {
+			mux func() *http.ServeMux
+		}
+		c, err := di.New(
+			di.Provide(func(factory func() *http.ServeMux) *Lazy { return &Lazy{mux: factory} }),
+			di.Provide(func(lazy *Lazy) *http.ServeMux { return &http.ServeMux{} }),
+		)
+		require.NoError(t, err)
+		var lazy *Lazy
+		require.NoError(t, c.Resolve(&lazy))
+		require.NotNil(t, lazy.mux())
+	})
+}
+
+func TestContainer_PreferLast(t *testing.T) {
+	t.Run("resolve last registered type", func(t *testing.T) {
+		last := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+			di.Provide(func() *http.Server { return last }),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.Error(t, c.Resolve(&server))
+		require.NoError(t, c.Resolve(&server, di.PreferLast()))
+		require.Equal(t, fmt.Sprintf("%p", last), fmt.Sprintf("%p", server))
+	})
+
+	t.Run("resolve option not applied to dependencies", func(t *testing.T) {
+		type Service struct{ *http.Server }
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+			di.Provide(func() *http.Server { return &http.Server{} }),
+			di.Provide(func(server *http.Server) *Service { return &Service{server} }),
+		)
+		require.NoError(t, err)
+		var service *Service
+		err = c.Resolve(&service, di.PreferLast())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "multiple definitions of *http.Server")
+	})
+
+	t.Run("container option applied to dependencies", func(t *testing.T) {
+		type Service struct{ *http.Server }
+		last := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+			di.Provide(func() *http.Server { return last }),
+			di.Provide(func(server *http.Server) *Service { return &Service{server} }),
+			di.PreferLast(),
+		)
+		require.NoError(t, err)
+		var service *Service
+		require.NoError(t, c.Resolve(&service))
+		require.Equal(t, fmt.Sprintf("%p", last), fmt.Sprintf("%p", service.Server))
+	})
+
+	t.Run("last registered type with tags", func(t *testing.T) {
+		last := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.Tags{"name": "server"}),
+			di.Provide(func() *http.Server { return last }, di.Tags{"name": "server"}),
+			di.Provide(func() *http.Server { return &http.Server{} }, di.Tags{"name": "other"}),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server, di.Name("server"), di.PreferLast()))
+		require.Equal(t, fmt.Sprintf("%p", last), fmt.Sprintf("%p", server))
+	})
+
+	t.Run("group contains all types", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+			di.Provide(func() *http.Server { return &http.Server{} }),
+			di.PreferLast(),
+		)
+		require.NoError(t, err)
+		var servers []*http.Server
+		require.NoError(t, c.Resolve(&servers))
+		require.Len(t, servers, 2)
+	})
+}
+
+func TestContainer_ScopeName(t *testing.T) {
+	t.Run("root container scope name", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		var name di.ScopeName
+		require.NoError(t, c.Resolve(&name))
+		require.Equal(t, di.ScopeName("root"), name)
+	})
+
+	t.Run("inject scope name of child container", func(t *testing.T) {
+		type Logger struct{ prefix string }
+		parent, err := di.New()
+		require.NoError(t, err)
+		child, err := di.New(
+			di.Scope("tenant-1"),
+			di.Provide(func(scope di.ScopeName) *Logger { return &Logger{prefix: string(scope)} }),
+		)
+		require.NoError(t, err)
+		require.NoError(t, child.AddParent(parent))
+		var logger *Logger
+		require.NoError(t, child.Resolve(&logger))
+		require.Equal(t, "tenant-1", logger.prefix)
+		var name di.ScopeName
+		require.NoError(t, parent.Resolve(&name))
+		require.Equal(t, di.ScopeName("root"), name)
+	})
+
+	t.Run("provide scope name cause error", func(t *testing.T) {
+		_, err := di.New(
+			di.ProvideValue(di.ScopeName("custom")),
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "di.ScopeName is injected by container and can't be provided")
+	})
+}
+
+func TestContainer_Strict(t *testing.T) {
+	t.Run("constructor returned nil cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return nil }),
+			di.DisallowNil(),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		err = c.Resolve(&server)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*http.Server is nil, nil instances are disallowed")
+	})
+
+	t.Run("pointer to interface not resolved in strict mode", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *os.File { return &os.File{} }, di.As(new(io.Writer))),
+			di.Strict(),
+		)
+		require.NoError(t, err)
+		var writer *io.Writer
+		err = c.Resolve(&writer)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+	})
+}
+
+func TestNewTest(t *testing.T) {
+	t.Run("resolve and cleanup", func(t *testing.T) {
+		var cleaned bool
+		t.Run("test", func(t *testing.T) {
+			c := di.NewTest(t,
+				di.Provide(func() (*http.Server, func()) {
+					return &http.Server{}, func() { cleaned = true }
+				}),
+			)
+			var server *http.Server
+			c.Resolve(&server)
+			require.NotNil(t, server)
+			require.False(t, cleaned)
+		})
+		require.True(t, cleaned)
+	})
+
+	t.Run("resolve error fails test", func(t *testing.T) {
+		tb := &fakeTB{TB: t}
+		c := di.NewTest(tb)
+		var server *http.Server
+		c.Resolve(&server)
+		require.Len(t, tb.fatals, 1)
+		require.Contains(t, tb.fatals[0], "type *http.Server not exists in the container")
+	})
+
+	t.Run("container error fails test", func(t *testing.T) {
+		tb := &fakeTB{TB: t}
+		c := di.NewTest(tb, di.Provide(func() {}))
+		require.Nil(t, c)
+		require.Len(t, tb.fatals, 1)
+		require.Contains(t, tb.fatals[0], "invalid constructor signature")
+	})
+
+	t.Run("assert resolution order", func(t *testing.T) {
+		c := di.NewTest(t,
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+		)
+		var server *http.Server
+		require.True(t, di.AssertOrder(t, c.Container, &server, []reflect.Type{
+			reflect.TypeOf(new(http.ServeMux)),
+			reflect.TypeOf(new(http.Server)),
+		}))
+		tb := &fakeTB{TB: t}
+		require.False(t, di.AssertOrder(tb, c.Container, &server, []reflect.Type{
+			reflect.TypeOf(new(http.Server)),
+			reflect.TypeOf(new(http.ServeMux)),
+		}))
+		require.Len(t, tb.errors, 1)
+		require.Contains(t, tb.errors[0], "resolution order mismatch:\nexpected: [*http.Server *http.ServeMux]\nactual:   [*http.ServeMux *http.Server]")
+	})
+}
+
+// fakeTB records errors instead of failing test.
+type fakeTB struct {
+	testing.TB
+	fatals []string
+	errors []string
+}
+
+func (tb *fakeTB) Fatal(args ...interface{}) {
+	tb.fatals = append(tb.fatals, fmt.Sprint(args...))
+}
+
+func (tb *fakeTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestContainer_Inspect(t *testing.T) {
+	t.Run("inspect provided types", func(t *testing.T) {
+		type Parameters struct {
+			di.Inject
+			Server *http.Server `di:"name=public"`
+			File   *os.File     `di:"optional"`
+		}
+		type Module struct {
+			Mux func() *http.ServeMux
+		}
+		c, err := di.New(
+			di.ProvideStruct(Module{Mux: func() *http.ServeMux { return &http.ServeMux{} }}),
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }, di.Tags{"name": "public"}),
+			di.Provide(func(params Parameters) *net.TCPConn { return &net.TCPConn{} }),
+		)
+		require.NoError(t, err)
+		definitions := c.Inspect()
+		require.Len(t, definitions, 4)
+		require.Equal(t, reflect.TypeOf(new(di.Container)), definitions[0].Type)
+		require.Equal(t, reflect.TypeOf(new(http.ServeMux)), definitions[1].Type)
+		require.Equal(t, "di_test.Module", definitions[1].Module)
+		require.Equal(t, "singleton", definitions[1].Lifetime.String())
+		require.Equal(t, di.Definition{
+			Type: reflect.TypeOf(new(http.Server)),
+			Tags: di.Tags{"name": "public"},
+			Dependencies: []di.Dependency{
+				{Type: reflect.TypeOf(new(http.ServeMux)), Tags: di.Tags{}},
+			},
+			Lifetime: di.LifetimeSingleton,
+		}, definitions[2])
+		require.Equal(t, reflect.TypeOf(new(net.TCPConn)), definitions[3].Type)
+		require.Equal(t, []di.Dependency{
+			{Type: reflect.TypeOf(Parameters{}), Tags: di.Tags{}},
+		}, definitions[3].Dependencies)
+	})
+
+	t.Run("inspect provided injectable", func(t *testing.T) {
+		type Parameters struct {
+			di.Inject
+			Server *http.Server `di:"name=public"`
+			File   *os.File     `di:"optional"`
+		}
+		c, err := di.New(
+			di.Provide(func() *Parameters { return &Parameters{} }),
+		)
+		require.NoError(t, err)
+		definitions := c.Inspect()
+		require.Len(t, definitions, 2)
+		require.Equal(t, []di.Dependency{
+			{Type: reflect.TypeOf(new(http.Server)), Tags: di.Tags{"name": "public"}},
+			{Type: reflect.TypeOf(new(os.File)), Tags: di.Tags{}, Optional: true},
+		}, definitions[1].Dependencies)
+	})
+}
+
+func TestContainer_UseInterceptor(t *testing.T) {
+	t.Run("interceptors wrap construction in order", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }, di.Tags{"name": "public"}),
+		)
+		require.NoError(t, err)
+		var calls []string
+		c.UseInterceptor(func(next di.ConstructFunc) di.ConstructFunc {
+			return func(info di.ConstructInfo) (reflect.Value, error) {
+				calls = append(calls, fmt.Sprintf("outer %s%s", info.Type, info.Tags))
+				return next(info)
+			}
+		})
+		c.UseInterceptor(func(next di.ConstructFunc) di.ConstructFunc {
+			return func(info di.ConstructInfo) (reflect.Value, error) {
+				calls = append(calls, fmt.Sprintf("inner %s", info.Type))
+				return next(info)
+			}
+		})
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server, di.Name("public")))
+		require.NotNil(t, server.Handler)
+		require.Equal(t, []string{
+			"outer *http.ServeMux",
+			"inner *http.ServeMux",
+			"outer *http.Server[name:public]",
+			"inner *http.Server",
+		}, calls)
+		calls = nil
+		require.NoError(t, c.Resolve(&server, di.Name("public")))
+		require.Empty(t, calls)
+	})
+
+	t.Run("interceptor error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		c.UseInterceptor(func(next di.ConstructFunc) di.ConstructFunc {
+			return func(info di.ConstructInfo) (reflect.Value, error) {
+				return reflect.Value{}, errors.New("construction is not allowed")
+			}
+		})
+		var server *http.Server
+		err = c.Resolve(&server)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*http.Server: construction is not allowed")
+	})
+}
+
+// dbConfig is a configuration section with validation.
+type dbConfig struct {
+	DSN string
+}
+
+func (c dbConfig) Validate() error {
+	if c.DSN == "" {
+		return errors.New("dsn is required")
+	}
+	return nil
+}
+
+func TestProvideSection(t *testing.T) {
+	type AppConfig struct {
+		DB dbConfig
+	}
+
+	t.Run("resolve section", func(t *testing.T) {
+		c, err := di.New(
+			di.ProvideValue(AppConfig{DB: dbConfig{DSN: "postgres://localhost"}}),
+			di.ProvideSection[dbConfig](func(config AppConfig) dbConfig { return config.DB }),
+		)
+		require.NoError(t, err)
+		require.Contains(t, c.Types(), reflect.TypeOf(dbConfig{}))
+		var config dbConfig
+		require.NoError(t, c.Resolve(&config))
+		require.Equal(t, "postgres://localhost", config.DSN)
+	})
+
+	t.Run("invalid section cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.ProvideValue(AppConfig{}),
+			di.ProvideSection[dbConfig](func(config AppConfig) dbConfig { return config.DB }),
+		)
+		require.NoError(t, err)
+		var config dbConfig
+		err = c.Resolve(&config)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid section di_test.dbConfig: dsn is required")
+	})
+}
+
+func TestForEach(t *testing.T) {
+	t.Run("iterate group members", func(t *testing.T) {
+		server := &http.Server{}
+		file := &os.File{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return server }, di.As(new(io.Closer))),
+			di.Provide(func() *os.File { return file }, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		var closers []string
+		require.NoError(t, di.ForEach(c, func(closer io.Closer) error {
+			closers = append(closers, fmt.Sprintf("%p", closer))
+			return nil
+		}))
+		require.Equal(t, []string{fmt.Sprintf("%p", server), fmt.Sprintf("%p", file)}, closers)
+	})
+
+	t.Run("stop on first error", func(t *testing.T) {
+		var built bool
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.As(new(io.Closer))),
+			di.Provide(func() *os.File {
+				built = true
+				return &os.File{}
+			}, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		err = di.ForEach(c, func(closer io.Closer) error {
+			return errors.New("init failed")
+		})
+		require.EqualError(t, err, "init failed")
+		require.False(t, built)
+	})
+
+	t.Run("iterate slice provided directly", func(t *testing.T) {
+		c, err := di.New(
+			di.ProvideValue([]string{"a", "b"}),
+		)
+		require.NoError(t, err)
+		var items []string
+		require.NoError(t, di.ForEach(c, func(item string) error {
+			items = append(items, item)
+			return nil
+		}))
+		require.Equal(t, []string{"a", "b"}, items)
+	})
+
+	t.Run("iterate not existing group cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		err = di.ForEach(c, func(closer io.Closer) error { return nil })
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+	})
+
+	t.Run("nil members are skipped with option", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.As(new(io.Closer))),
+			di.Provide(func() *os.File { return nil }, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		var closers []io.Closer
+		require.NoError(t, di.ForEach(c, func(closer io.Closer) error {
+			closers = append(closers, closer)
+			return nil
+		}, di.SkipNilMembers()))
+		require.Len(t, closers, 1)
+		require.NotNil(t, closers[0])
+	})
+}
+
+func TestFederate(t *testing.T) {
+	t.Run("resolve types from containers in order", func(t *testing.T) {
+		hostServer := &http.Server{}
+		mux := &http.ServeMux{}
+		host, err := di.New(
+			di.Provide(func() *http.Server { return hostServer }),
+		)
+		require.NoError(t, err)
+		plugin, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+			di.Provide(func() *http.ServeMux { return mux }),
+		)
+		require.NoError(t, err)
+		resolver := di.Federate(host, plugin)
+		var server *http.Server
+		require.NoError(t, resolver.Resolve(&server))
+		require.Equal(t, fmt.Sprintf("%p", hostServer), fmt.Sprintf("%p", server))
+		has, err := resolver.Has(new(*http.ServeMux))
+		require.NoError(t, err)
+		require.True(t, has)
+		req := &http.Request{}
+		require.NoError(t, resolver.Invoke(func(s *http.Server, m *http.ServeMux, r *http.Request) {
+			require.Equal(t, fmt.Sprintf("%p", hostServer), fmt.Sprintf("%p", s))
+			require.Equal(t, fmt.Sprintf("%p", mux), fmt.Sprintf("%p", m))
+			require.Equal(t, fmt.Sprintf("%p", req), fmt.Sprintf("%p", r))
+		}, di.With(req)))
+	})
+
+	t.Run("resolve not existing type cause error", func(t *testing.T) {
+		host, err := di.New()
+		require.NoError(t, err)
+		resolver := di.Federate(host)
+		var mux *http.ServeMux
+		err = resolver.Resolve(&mux)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+		err = resolver.Invoke(func(mux *http.ServeMux) {})
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+		has, err := resolver.Has(&mux)
+		require.NoError(t, err)
+		require.False(t, has)
+	})
+
+	t.Run("invoke with error", func(t *testing.T) {
+		host, err := di.New()
+		require.NoError(t, err)
+		err = di.Federate(host).Invoke(func() error { return errors.New("invoke failed") })
+		require.EqualError(t, err, "invoke failed")
+		err = di.Federate(host).Invoke(func() *http.Server { return nil })
+		require.Contains(t, err.Error(), "invalid invocation signature, got func() *http.Server")
+	})
+}
+
+func TestContainer_Registry(t *testing.T) {
+	t.Run("inject registry", func(t *testing.T) {
+		type Diagnostics struct {
+			di.Registry
+		}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.As(new(io.Closer))),
+			di.Provide(func(registry di.Registry) *Diagnostics { return &Diagnostics{registry} }),
+		)
+		require.NoError(t, err)
+		var diagnostics *Diagnostics
+		require.NoError(t, c.Resolve(&diagnostics))
+		require.Equal(t, c.Types(), diagnostics.Types())
+		require.Equal(t, c.Inspect(), diagnostics.Definitions())
+		size, err := diagnostics.GroupSize(reflect.TypeOf(new(io.Closer)).Elem())
+		require.NoError(t, err)
+		require.Equal(t, 1, size)
+		require.NotContains(t, diagnostics.Types(), reflect.TypeOf(new(di.Registry)).Elem())
+	})
+
+	t.Run("provide registry cause error", func(t *testing.T) {
+		type Registry struct {
+			di.Registry
+		}
+		_, err := di.New(
+			di.Provide(func() *Registry { return &Registry{} }, di.As(new(di.Registry))),
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "di.Registry is injected by container and can't be provided")
+	})
+}
+
+func TestResolveAs(t *testing.T) {
+	t.Run("resolve as richer interface", func(t *testing.T) {
+		file := &os.File{}
+		c, err := di.New(
+			di.Provide(func() *os.File { return file }, di.As(new(io.Reader))),
+		)
+		require.NoError(t, err)
+		closer, err := di.ResolveAs[io.ReadCloser](c, new(io.Reader))
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%p", file), fmt.Sprintf("%p", closer))
+	})
+
+	t.Run("resolve as not implemented interface cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *bytes.Buffer { return &bytes.Buffer{} }, di.As(new(io.Reader))),
+		)
+		require.NoError(t, err)
+		_, err = di.ResolveAs[io.ReadCloser](c, new(io.Reader))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "io.Reader resolved as *bytes.Buffer not implement io.ReadCloser")
+	})
+
+	t.Run("resolve as not interface cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		_, err = di.ResolveAs[*os.File](c, new(io.Reader))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "type can be resolved as interface, got *os.File")
+	})
+}
+
+func TestOnResolve(t *testing.T) {
+	t.Run("observe construction with labels", func(t *testing.T) {
+		var events []di.ResolveEvent
+		c, err := di.New(
+			di.OnResolve(func(event di.ResolveEvent) {
+				events = append(events, event)
+			}),
+			di.Provide(func() (*http.ServeMux, error) { return nil, errors.New("mux failed") }, di.Tags{"name": "mux"}),
+			di.Provide(func() *http.Server { return &http.Server{} }, di.WithLabels(map[string]string{"component": "http"})),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		var mux *http.ServeMux
+		require.Error(t, c.Resolve(&mux))
+		require.Len(t, events, 2)
+		require.Equal(t, reflect.TypeOf(server), events[0].Type)
+		require.Equal(t, map[string]string{"component": "http"}, events[0].Labels)
+		require.NoError(t, events[0].Err)
+		require.Equal(t, reflect.TypeOf(mux), events[1].Type)
+		require.Equal(t, di.Tags{"name": "mux"}, events[1].Tags)
+		require.EqualError(t, events[1].Err, "mux failed")
+	})
+}
+
+// httpHandler is a test http.Handler.
+type httpHandler struct{}
+
+func (h *httpHandler) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+func TestContainer_String(t *testing.T) {
+	c, err := di.New(
+		di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }, di.Tags{"name": "public"}, di.As(new(io.Closer))),
+		di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.As(new(http.Handler))),
+		di.ProvideValue("addr"),
+	)
+	require.NoError(t, err)
+	require.Equal(t, `*di.Container singleton, dependencies: 0
+*http.ServeMux singleton, dependencies: 0, groups: []http.Handler
+*http.Server[name:public] singleton, dependencies: 1, groups: []io.Closer
+string singleton, dependencies: 0`, c.String())
+}
+
+func TestContainer_LockOSThread(t *testing.T) {
+	t.Run("construct and cleanup on locked thread", func(t *testing.T) {
+		var constructed, cleaned bool
+		c, err := di.New(
+			di.Provide(func() (*http.Server, func() error) {
+				constructed = true
+				return &http.Server{}, func() error {
+					cleaned = true
+					return nil
+				}
+			}, di.LockOSThread(), di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		var closer io.Closer
+		require.NoError(t, c.Resolve(&closer))
+		require.True(t, constructed)
+		require.NoError(t, c.Cleanup())
+		require.True(t, cleaned)
+	})
+
+	t.Run("constructor error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() (*http.Server, error) {
+				return nil, errors.New("server failed")
+			}, di.LockOSThread()),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		err = c.Resolve(&server)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "server failed")
+	})
+
+	t.Run("constructor panic", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server {
+				panic("server panic")
+			}, di.LockOSThread()),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.PanicsWithValue(t, "server panic", func() {
+			_ = c.Resolve(&server)
+		})
+	})
+
+	t.Run("repeated cleanup runs locked cleanup once", func(t *testing.T) {
+		var cleanups int
+		c, err := di.New(
+			di.Provide(func() (*http.Server, func()) {
+				return &http.Server{}, func() { cleanups++ }
+			}, di.LockOSThread()),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		require.NoError(t, c.Cleanup())
+		require.NotPanics(t, func() {
+			require.NoError(t, c.Cleanup())
+		})
+		require.Equal(t, 1, cleanups)
+	})
+}
+
+func TestContainer_ResolveByName(t *testing.T) {
+	server := &http.Server{}
+	c, err := di.New(
+		di.Provide(func() *http.Server { return server }, di.Tags{"name": "public"}),
+		di.Provide(func() *http.Server { return &http.Server{} }, di.Tags{"name": "private"}),
+	)
+	require.NoError(t, err)
+
+	t.Run("resolve by type name", func(t *testing.T) {
+		instance, err := c.ResolveByName("*http.Server", di.Name("public"))
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", instance))
+	})
+
+	t.Run("resolve by qualified type name", func(t *testing.T) {
+		instance, err := c.ResolveByName("*net/http.Server", di.Name("public"))
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", instance))
+	})
+
+	t.Run("resolve by unknown name cause error", func(t *testing.T) {
+		_, err := c.ResolveByName("*http.ServeMux")
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+	})
+
+	t.Run("resolve by ambiguous name cause error", func(t *testing.T) {
+		first := func() di.Option {
+			type Server struct{}
+			return di.Provide(func() *Server { return &Server{} })
+		}
+		second := func() di.Option {
+			type Server struct{}
+			return di.Provide(func() *Server { return &Server{} })
+		}
+		c, err := di.New(first(), second())
+		require.NoError(t, err)
+		_, err = c.ResolveByName("*di_test.Server")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "type name *di_test.Server is ambiguous")
+	})
+}
+
+func TestContainer_SetProviders(t *testing.T) {
+	closerType := reflect.TypeOf(new(io.Closer)).Elem()
+
+	t.Run("replace group members", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.As(new(io.Closer))),
+			di.Provide(func() *os.File { return &os.File{} }, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers))
+		require.Len(t, closers, 2)
+		err = c.SetProviders(closerType, []di.Provider{
+			{Constructor: func() *net.TCPConn { return &net.TCPConn{} }, Options: []di.ProvideOption{di.As(new(io.Closer))}},
+		})
+		require.NoError(t, err)
+		require.NoError(t, c.Resolve(&closers))
+		require.Len(t, closers, 1)
+		require.IsType(t, &net.TCPConn{}, closers[0])
+		has, err := c.Has(new(*http.Server))
+		require.NoError(t, err)
+		require.False(t, has)
+	})
+
+	t.Run("dependents are rebuilt", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+		mux := &http.ServeMux{}
+		err = c.SetProviders(reflect.TypeOf(mux), []di.Provider{
+			{Constructor: func() *http.ServeMux { return mux }},
+		})
+		require.NoError(t, err)
+		require.NoError(t, c.Resolve(&server))
+		require.Equal(t, fmt.Sprintf("%p", mux), fmt.Sprintf("%p", server.Handler))
+	})
+
+	t.Run("provider of another type cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		err = c.SetProviders(closerType, []di.Provider{
+			{Constructor: func() *http.ServeMux { return &http.ServeMux{} }},
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*http.ServeMux not provide io.Closer")
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers))
+		require.Len(t, closers, 1)
+	})
+
+	t.Run("nil constructor cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		err = c.SetProviders(closerType, []di.Provider{{}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid constructor signature, got nil")
+	})
+
+	t.Run("concurrent resolve sees full set", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.As(new(io.Closer))),
+			di.Provide(func() *os.File { return &os.File{} }, di.As(new(io.Closer))),
+		)
+		require.NoError(t, err)
+		providers := []di.Provider{
+			{Constructor: func() *net.TCPConn { return &net.TCPConn{} }, Options: []di.ProvideOption{di.As(new(io.Closer))}},
+			{Constructor: func() *net.UDPConn { return &net.UDPConn{} }, Options: []di.ProvideOption{di.As(new(io.Closer))}},
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var closers []io.Closer
+				require.NoError(t, c.Resolve(&closers))
+				require.Len(t, closers, 2)
+				_, old := closers[0].(*http.Server)
+				_, oldFile := closers[1].(*os.File)
+				require.Equal(t, old, oldFile)
+			}()
+		}
+		require.NoError(t, c.SetProviders(closerType, providers))
+		wg.Wait()
+	})
+}
+
+func TestContainer_HealthCheck(t *testing.T) {
+	t.Run("run health checks", func(t *testing.T) {
+		var calls []string
+		c, err := di.New(
+			di.ProvideHealthCheck("database", func() error {
+				calls = append(calls, "database")
+				return nil
+			}),
+			di.ProvideHealthCheck("cache", func() error {
+				calls = append(calls, "cache")
+				return nil
+			}),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.HealthCheck())
+		require.Equal(t, []string{"database", "cache"}, calls)
+	})
+
+	t.Run("errors are joined by name", func(t *testing.T) {
+		pingErr := errors.New("connection refused")
+		c, err := di.New(
+			di.ProvideHealthCheck("database", func() error { return pingErr }),
+			di.ProvideHealthCheck("cache", func() error { return nil }),
+			di.Provide(func() di.HealthCheck {
+				return func() error { return errors.New("queue is full") }
+			}, di.Tags{"health": "queue"}),
+		)
+		require.NoError(t, err)
+		err = c.HealthCheck()
+		require.Error(t, err)
+		require.Equal(t, "database health check: connection refused\nqueue health check: queue is full", err.Error())
+	})
+
+	t.Run("single failed health check", func(t *testing.T) {
+		pingErr := errors.New("connection refused")
+		c, err := di.New(
+			di.ProvideHealthCheck("database", func() error { return pingErr }),
+		)
+		require.NoError(t, err)
+		err = c.HealthCheck()
+		require.True(t, errors.Is(err, pingErr))
+	})
+
+	t.Run("no health checks", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		require.NoError(t, c.HealthCheck())
+	})
+
+	t.Run("failed health check constructor cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() (di.HealthCheck, error) {
+				return nil, errors.New("not configured")
+			}, di.Tags{"health": "queue"}),
+		)
+		require.NoError(t, err)
+		err = c.HealthCheck()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "di.HealthCheck[health:queue]: not configured")
+	})
+}
+
+func TestResolveType(t *testing.T) {
+	t.Run("resolve runtime type", func(t *testing.T) {
+		server := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return server }, di.As(new(io.Closer)), di.WithName("public")),
+		)
+		require.NoError(t, err)
+		value, err := di.ResolveType(c, reflect.TypeOf(server), di.Name("public"))
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", value.Interface()))
+		value, err = di.ResolveType(c, reflect.TypeOf(new(io.Closer)).Elem())
+		require.NoError(t, err)
+		require.Equal(t, reflect.TypeOf(new(io.Closer)).Elem(), value.Type())
+		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", value.Interface()))
+	})
+
+	t.Run("resolve not existing type cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		_, err = di.ResolveType(c, reflect.TypeOf(&http.Server{}))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+	})
+
+	t.Run("resolve nil type cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		_, err = di.ResolveType(c, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid type, got nil")
+	})
+}
+
+func TestContainer_InvokeWithTrace(t *testing.T) {
+	t.Run("trace chosen dependencies", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.WithName("public"), di.As(new(http.Handler))),
+			di.Provide(func() *net.TCPConn { return &net.TCPConn{} }, di.As(new(io.Closer))),
+			di.Provide(func() *os.File { return &os.File{} }, di.As(new(io.Closer)), di.GroupMemberIf(func() bool { return false })),
+			di.Provide(func(handler http.Handler, closers []io.Closer) *http.Server { return &http.Server{Handler: handler} }),
+		)
+		require.NoError(t, err)
+		var mux *http.ServeMux
+		require.NoError(t, c.Resolve(&mux))
+		trace, err := c.InvokeWithTrace(func(server *http.Server) {})
+		require.NoError(t, err)
+		require.Equal(t, "func(*http.Server)\n  *http.Server\n    http.Handler[name:public] (cached)\n    []io.Closer\n      io.Closer", trace.String())
+		require.Equal(t, reflect.TypeOf(&http.Server{}), trace.Dependencies[0].Type)
+		require.False(t, trace.Dependencies[0].Cached)
+		require.Equal(t, di.Tags{"name": "public"}, trace.Dependencies[0].Dependencies[0].Tags)
+		trace, err = c.InvokeWithTrace(func(server *http.Server) {})
+		require.NoError(t, err)
+		require.True(t, trace.Dependencies[0].Cached)
+	})
+
+	t.Run("trace injected fields", func(t *testing.T) {
+		type Params struct {
+			di.Inject
+			Mux  *http.ServeMux `di:"name=second"`
+			Conn *net.TCPConn   `di:"optional"`
+		}
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.WithName("first")),
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.WithName("second")),
+		)
+		require.NoError(t, err)
+		trace, err := c.InvokeWithTrace(func(params Params) {})
+		require.NoError(t, err)
+		require.Len(t, trace.Dependencies, 1)
+		require.Len(t, trace.Dependencies[0].Dependencies, 1)
+		require.Equal(t, di.Tags{"name": "second"}, trace.Dependencies[0].Dependencies[0].Tags)
+	})
+
+	t.Run("trace is returned on invocation error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+		)
+		require.NoError(t, err)
+		trace, err := c.InvokeWithTrace(func(mux *http.ServeMux) error { return errors.New("failed") })
+		require.Error(t, err)
+		require.NotNil(t, trace)
+		require.Len(t, trace.Dependencies, 1)
+	})
+
+	t.Run("not existing dependency cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		trace, err := c.InvokeWithTrace(func(mux *http.ServeMux) {})
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+		require.Nil(t, trace)
+	})
+
+	t.Run("trace types resolved by factory on construction", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.WithName("public")),
+			di.Provide(func(factory func() *http.ServeMux) *http.Server { return &http.Server{Handler: factory()} }),
+		)
+		require.NoError(t, err)
+		trace, err := c.InvokeWithTrace(func(server *http.Server) {})
+		require.NoError(t, err)
+		require.Equal(t, "func(*http.Server)\n  *http.Server\n    func() *http.ServeMux\n      *http.ServeMux[name:public]", trace.String())
+	})
+
+	t.Run("trace instances cached by scope", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.Scoped()),
+		)
+		require.NoError(t, err)
+		first, err := c.Scope()
+		require.NoError(t, err)
+		var mux *http.ServeMux
+		require.NoError(t, first.Resolve(&mux))
+		trace, err := first.InvokeWithTrace(func(mux *http.ServeMux) {})
+		require.NoError(t, err)
+		require.True(t, trace.Dependencies[0].Cached)
+		second, err := c.Scope()
+		require.NoError(t, err)
+		trace, err = second.InvokeWithTrace(func(mux *http.ServeMux) {})
+		require.NoError(t, err)
+		require.False(t, trace.Dependencies[0].Cached)
+	})
+}
+
+func TestGlobalRegister(t *testing.T) {
+	t.Run("import global registrations", func(t *testing.T) {
+		defer di.ResetGlobalRegistrations()
+		di.GlobalRegister(func() *net.TCPConn { return &net.TCPConn{} }, di.As(new(io.Closer)))
+		di.GlobalRegister(func() *os.File { return &os.File{} }, di.As(new(io.Closer)))
+		c, err := di.New(
+			di.WithGlobalRegistrations(),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.Apply(di.WithGlobalRegistrations()))
+		var closers []io.Closer
+		require.NoError(t, c.Resolve(&closers))
+		require.Len(t, closers, 2)
+	})
+
+	t.Run("container without option doesn't import registrations", func(t *testing.T) {
+		defer di.ResetGlobalRegistrations()
+		di.GlobalRegister(func() *net.TCPConn { return &net.TCPConn{} })
+		c, err := di.New()
+		require.NoError(t, err)
+		has, err := c.Has(new(*net.TCPConn))
+		require.NoError(t, err)
+		require.False(t, has)
+	})
+
+	t.Run("reset global registrations", func(t *testing.T) {
+		di.GlobalRegister(func() *net.TCPConn { return &net.TCPConn{} })
+		di.ResetGlobalRegistrations()
+		c, err := di.New(di.WithGlobalRegistrations())
+		require.NoError(t, err)
+		has, err := c.Has(new(*net.TCPConn))
+		require.NoError(t, err)
+		require.False(t, has)
+	})
+
+	t.Run("invalid global constructor cause error", func(t *testing.T) {
+		defer di.ResetGlobalRegistrations()
+		di.GlobalRegister(func() {})
+		_, err := di.New(di.WithGlobalRegistrations())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "container_test.go:")
+		require.Contains(t, err.Error(), "invalid constructor signature")
+	})
+}
+
+func TestBuild(t *testing.T) {
+	type Config struct {
+		di.Inject
+		Server *http.Server
+		Mux    *http.ServeMux `di:"name=public"`
+		Conn   *net.TCPConn   `di:"optional"`
+		Port   int            `di:"skip"`
+	}
+
+	t.Run("build struct with literal fields", func(t *testing.T) {
+		server := &http.Server{}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return server }),
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.WithName("public")),
+		)
+		require.NoError(t, err)
+		mux := &http.ServeMux{}
+		config, err := di.Build[*Config](c, di.Set("Port", 8080), di.Set("Mux", mux))
+		require.NoError(t, err)
+		require.Equal(t, 8080, config.Port)
+		require.Equal(t, fmt.Sprintf("%p", server), fmt.Sprintf("%p", config.Server))
+		require.Equal(t, fmt.Sprintf("%p", mux), fmt.Sprintf("%p", config.Mux))
+		require.Nil(t, config.Conn)
+	})
+
+	t.Run("build struct value", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.WithName("public")),
+		)
+		require.NoError(t, err)
+		config, err := di.Build[Config](c, di.Set("Server", nil))
+		require.NoError(t, err)
+		require.Nil(t, config.Server)
+		require.NotNil(t, config.Mux)
+	})
+
+	t.Run("not existing field dependency cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		_, err = di.Build[*Config](c, di.Set("Server", &http.Server{}))
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+	})
+
+	t.Run("unknown field cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		_, err = di.Build[*Config](c, di.Set("Unknown", 1))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "has no exported field Unknown")
+	})
+
+	t.Run("not assignable value cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		_, err = di.Build[*Config](c, di.Set("Port", "8080"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), ".Port: string not assignable to int")
+	})
+
+	t.Run("build type without inject cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		_, err = di.Build[*http.Server](c)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "struct with di.Inject can be built, got *http.Server")
+	})
+
+	t.Run("build with unexported field cause error", func(t *testing.T) {
+		type Config struct {
+			di.Inject
+			Server *http.Server
+			mux    *http.ServeMux `di:""`
+		}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		_, err = di.Build[*Config](c)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cannot inject unexported field mux of di_test.Config")
+	})
+}
+
+func TestContainer_Weighted(t *testing.T) {
+	newContainer := func(t *testing.T, seed int64) *di.Container {
+		c, err := di.New(
+			di.WeightSeed(seed),
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.As(new(http.Handler)), di.Weight(3)),
+			di.Provide(func() *httpHandler { return &httpHandler{} }, di.As(new(http.Handler))),
+		)
+		require.NoError(t, err)
+		return c
+	}
+	pick := func(t *testing.T, c *di.Container, count int) (picks []string) {
+		for i := 0; i < count; i++ {
+			var handler http.Handler
+			require.NoError(t, c.Resolve(&handler, di.Weighted()))
+			picks = append(picks, fmt.Sprintf("%T", handler))
+		}
+		return picks
+	}
+
+	t.Run("select by weight", func(t *testing.T) {
+		c := newContainer(t, 1)
+		counts := map[string]int{}
+		for _, picked := range pick(t, c, 400) {
+			counts[picked]++
+		}
+		require.Len(t, counts, 2)
+		require.Greater(t, counts["*http.ServeMux"], counts["*di_test.httpHandler"])
+	})
+
+	t.Run("seed makes selection reproducible", func(t *testing.T) {
+		require.Equal(t, pick(t, newContainer(t, 42), 20), pick(t, newContainer(t, 42), 20))
+	})
+
+	t.Run("single definition is resolved", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.As(new(http.Handler))),
+		)
+		require.NoError(t, err)
+		var handler http.Handler
+		require.NoError(t, c.Resolve(&handler, di.Weighted()))
+	})
+
+	t.Run("multiple definitions without weighted cause error", func(t *testing.T) {
+		c := newContainer(t, 1)
+		var handler http.Handler
+		require.Error(t, c.Resolve(&handler))
+	})
+
+	t.Run("negative weight cause error", func(t *testing.T) {
+		_, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.Weight(-1)),
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid weight of *http.ServeMux, got -1: weight must not be negative")
+	})
+}
+
+func TestContainer_FailFast(t *testing.T) {
+	t.Run("missing dependencies are joined", func(t *testing.T) {
+		type Params struct {
+			di.Inject
+			Conn *net.TCPConn
+			File *os.File `di:"optional"`
+		}
+		_, err := di.New(
+			di.FailFast(),
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+			di.Provide(func(server *http.Server) *httpHandler { return &httpHandler{} }),
+			di.Provide(func(params Params) *net.UDPConn { return &net.UDPConn{} }),
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*http.Server: type *http.ServeMux not exists in the container\n")
+		require.Contains(t, err.Error(), "*net.UDPConn: di_test.Params: type *net.TCPConn not exists in the container")
+		require.NotContains(t, err.Error(), "*di_test.httpHandler")
+	})
+
+	t.Run("cycle cause error", func(t *testing.T) {
+		_, err := di.New(
+			di.FailFast(),
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+			di.Provide(func(server *http.Server) *http.ServeMux { return &http.ServeMux{} }),
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cycle detected")
+	})
+
+	t.Run("valid graph is not built", func(t *testing.T) {
+		var calls int
+		c, err := di.New(
+			di.FailFast(),
+			di.Provide(func() *http.ServeMux {
+				calls++
+				return &http.ServeMux{}
+			}),
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+		)
+		require.NoError(t, err)
+		require.Equal(t, 0, calls)
+		var server *http.Server
+		require.NoError(t, c.Resolve(&server))
+	})
+
+	t.Run("graph is validated lazily without option", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+		)
+		require.NoError(t, err)
+		var server *http.Server
+		require.Error(t, c.Resolve(&server))
+	})
+
+	t.Run("scoped type depends on type provided by scope", func(t *testing.T) {
+		c, err := di.New(
+			di.FailFast(),
+			di.Provide(func(req *http.Request) *bytes.Buffer { return bytes.NewBufferString(req.URL.Path) }, di.Scoped()),
+		)
+		require.NoError(t, err)
+		scope, err := c.Scope(di.ProvideValue(newRequest("/users")))
+		require.NoError(t, err)
+		var buf *bytes.Buffer
+		require.NoError(t, scope.Resolve(&buf))
+		require.Equal(t, "/users", buf.String())
+	})
+}
+
+func TestContainer_AliasTags(t *testing.T) {
+	t.Run("resolve type by alias tags", func(t *testing.T) {
+		var calls int
+		c, err := di.New(
+			di.Provide(func() *http.Server {
+				calls++
+				return &http.Server{}
+			}, di.WithName("primary")),
+			di.Provide(func() *http.Server { return &http.Server{} }, di.WithName("secondary")),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.AliasTags(new(*http.Server), di.Tags{"name": "primary"}, di.Tags{"role": "writer"}))
+		var primary, writer *http.Server
+		require.NoError(t, c.Resolve(&writer, di.Tags{"role": "writer"}))
+		require.NoError(t, c.Resolve(&primary, di.Name("primary")))
+		require.Equal(t, fmt.Sprintf("%p", primary), fmt.Sprintf("%p", writer))
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("alias of interface shares instance", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.As(new(http.Handler)), di.WithName("public")),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.AliasTags(new(http.Handler), di.Tags{"name": "public"}, di.Tags{"api": "v1"}))
+		var mux *http.ServeMux
+		require.NoError(t, c.Resolve(&mux))
+		var handler http.Handler
+		require.NoError(t, c.Resolve(&handler, di.Tags{"api": "v1"}))
+		require.Equal(t, fmt.Sprintf("%p", mux), fmt.Sprintf("%p", handler))
+	})
+
+	t.Run("alias of not existing type cause error", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		err = c.AliasTags(new(*http.Server), di.Tags{"name": "primary"}, di.Tags{"role": "writer"})
+		require.Error(t, err)
+		require.True(t, errors.Is(err, di.ErrTypeNotExists))
+	})
+
+	t.Run("alias of group cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		err = c.AliasTags(new([]*http.Server), nil, di.Tags{"role": "writer"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "[]*http.Server can't be aliased, only provided types can")
+	})
+}
+
+func TestContainer_BuildPhased(t *testing.T) {
+	t.Run("build phases in order", func(t *testing.T) {
+		var built []string
+		c, err := di.New(
+			di.Provide(func(buf *bytes.Buffer) *http.Server {
+				built = append(built, "server")
+				return &http.Server{}
+			}, di.Phase("http", 3)),
+			di.Provide(func() *http.ServeMux {
+				built = append(built, "mux")
+				return &http.ServeMux{}
+			}, di.Phase("services", 2), di.Eager()),
+			di.Provide(func() *bytes.Buffer {
+				built = append(built, "buffer")
+				return &bytes.Buffer{}
+			}),
+			di.Provide(func() *net.TCPConn {
+				built = append(built, "conn")
+				return &net.TCPConn{}
+			}, di.Phase("infra", 1)),
+		)
+		require.NoError(t, err)
+		require.Empty(t, built)
+		require.NoError(t, c.BuildPhased())
+		require.Equal(t, []string{"conn", "mux", "buffer", "server"}, built)
+	})
+
+	t.Run("failed phase stops later phases", func(t *testing.T) {
+		var built []string
+		c, err := di.New(
+			di.Provide(func() (*net.TCPConn, error) {
+				return nil, errors.New("connection refused")
+			}, di.Phase("infra", 1)),
+			di.Provide(func() *http.Server {
+				built = append(built, "server")
+				return &http.Server{}
+			}, di.Phase("http", 2)),
+		)
+		require.NoError(t, err)
+		err = c.BuildPhased()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "phase infra: *net.TCPConn: connection refused")
+		require.Empty(t, built)
+	})
+}
+
+func TestContainer_Lifetimes(t *testing.T) {
+	t.Run("transient is built on each resolve", func(t *testing.T) {
+		var calls, cleanups int
+		c, err := di.New(
+			di.Provide(func() (*bytes.Buffer, func()) {
+				calls++
+				return &bytes.Buffer{}, func() { cleanups++ }
+			}, di.Transient()),
+		)
+		require.NoError(t, err)
+		var first, second *bytes.Buffer
+		require.NoError(t, c.Resolve(&first))
+		require.NoError(t, c.Resolve(&second))
+		require.Equal(t, 2, calls)
+		require.NotEqual(t, fmt.Sprintf("%p", first), fmt.Sprintf("%p", second))
+		require.NoError(t, c.Cleanup())
+		require.Equal(t, 2, cleanups)
+	})
+
+	t.Run("factory of transient builds new instances", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *bytes.Buffer { return &bytes.Buffer{} }, di.Transient()),
+		)
+		require.NoError(t, err)
+		var factory func() *bytes.Buffer
+		require.NoError(t, c.Resolve(&factory))
+		require.NotEqual(t, fmt.Sprintf("%p", factory()), fmt.Sprintf("%p", factory()))
+	})
+
+	t.Run("scoped is built once per scope", func(t *testing.T) {
+		var calls int
+		var cleanups []string
+		c, err := di.New(
+			di.Provide(func() (*http.ServeMux, func()) {
+				return &http.ServeMux{}, func() { cleanups = append(cleanups, "mux") }
+			}),
+			di.Provide(func(mux *http.ServeMux, req *http.Request) (*bytes.Buffer, func()) {
+				calls++
+				return &bytes.Buffer{}, func() { cleanups = append(cleanups, req.URL.Path) }
+			}, di.Scoped()),
+		)
+		require.NoError(t, err)
+		first, err := c.Scope(di.ProvideValue(newRequest("/first")))
+		require.NoError(t, err)
+		second, err := c.Scope(di.ProvideValue(newRequest("/second")))
+		require.NoError(t, err)
+		var a, b, other *bytes.Buffer
+		require.NoError(t, first.Resolve(&a))
+		require.NoError(t, first.Resolve(&b))
+		require.Equal(t, fmt.Sprintf("%p", a), fmt.Sprintf("%p", b))
+		require.NoError(t, second.Resolve(&other))
+		require.NotEqual(t, fmt.Sprintf("%p", a), fmt.Sprintf("%p", other))
+		require.Equal(t, 2, calls)
+		var muxA, muxB *http.ServeMux
+		require.NoError(t, first.Resolve(&muxA))
+		require.NoError(t, second.Resolve(&muxB))
+		require.Equal(t, fmt.Sprintf("%p", muxA), fmt.Sprintf("%p", muxB))
+		require.NoError(t, first.Cleanup())
+		require.Equal(t, []string{"/first"}, cleanups)
+		require.NoError(t, second.Cleanup())
+		require.NoError(t, c.Cleanup())
+		require.Equal(t, []string{"/first", "/second", "mux"}, cleanups)
+	})
+
+	t.Run("scoped resolved from container cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *bytes.Buffer { return &bytes.Buffer{} }, di.Scoped()),
+		)
+		require.NoError(t, err)
+		var buf *bytes.Buffer
+		err = c.Resolve(&buf)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*bytes.Buffer is scoped, resolve it from container.Scope()")
+	})
+
+	t.Run("singleton depends on scoped cause error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *bytes.Buffer { return &bytes.Buffer{} }, di.Scoped()),
+			di.Provide(func(buf *bytes.Buffer) *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		scope, err := c.Scope()
+		require.NoError(t, err)
+		var server *http.Server
+		err = scope.Resolve(&server)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*bytes.Buffer is scoped, resolve it from container.Scope()")
+	})
+
+	t.Run("types provided into scope use scope values", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		scope, err := c.Scope(
+			di.ProvideValue(newRequest("/users")),
+			di.Provide(func(req *http.Request) *bytes.Buffer { return bytes.NewBufferString(req.URL.Path) }),
+		)
+		require.NoError(t, err)
+		var buf *bytes.Buffer
+		require.NoError(t, scope.Resolve(&buf))
+		require.Equal(t, "/users", buf.String())
+		has, err := c.Has(new(*bytes.Buffer))
+		require.NoError(t, err)
+		require.False(t, has)
+	})
+
+	t.Run("scoped can't be eager", func(t *testing.T) {
+		_, err := di.New(
+			di.Provide(func() *bytes.Buffer { return &bytes.Buffer{} }, di.Scoped(), di.Eager()),
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*bytes.Buffer is scoped and can't be eager")
+	})
+
+	t.Run("inspect lifetimes", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *bytes.Buffer { return &bytes.Buffer{} }, di.Scoped()),
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.Transient()),
+		)
+		require.NoError(t, err)
+		lifetimes := map[reflect.Type]di.Lifetime{}
+		for _, def := range c.Inspect() {
+			lifetimes[def.Type] = def.Lifetime
+		}
+		require.Equal(t, di.LifetimeScoped, lifetimes[reflect.TypeOf(&bytes.Buffer{})])
+		require.Equal(t, di.LifetimeTransient, lifetimes[reflect.TypeOf(&http.ServeMux{})])
+		require.Equal(t, "scoped", di.LifetimeScoped.String())
+	})
+
+	t.Run("invoke builds transient dependency once per dependent", func(t *testing.T) {
+		var calls, cleanups int
+		c, err := di.New(
+			di.Provide(func() (*bytes.Buffer, func()) {
+				calls++
+				return &bytes.Buffer{}, func() { cleanups++ }
+			}, di.Transient()),
+			di.Provide(func(buf *bytes.Buffer) *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.Invoke(func(buf *bytes.Buffer) {}))
+		require.Equal(t, 1, calls)
+		require.NoError(t, c.Invoke(func(buf *bytes.Buffer, server *http.Server) {}))
+		require.Equal(t, 3, calls)
+		require.NoError(t, c.Cleanup())
+		require.Equal(t, 3, cleanups)
+	})
+
+	t.Run("warm skips transient types", func(t *testing.T) {
+		var calls int
+		c, err := di.New(
+			di.Provide(func() *bytes.Buffer {
+				calls++
+				return &bytes.Buffer{}
+			}, di.Transient()),
+			di.Provide(func(buf *bytes.Buffer) *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.Warm(new(*http.Server)))
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("scope resolves itself as container", func(t *testing.T) {
+		type Handler struct{ container *di.Container }
+		c, err := di.New(
+			di.Provide(func(container *di.Container) *Handler { return &Handler{container: container} }, di.Scoped()),
+		)
+		require.NoError(t, err)
+		scope, err := c.Scope()
+		require.NoError(t, err)
+		var container *di.Container
+		require.NoError(t, scope.Resolve(&container))
+		require.Equal(t, fmt.Sprintf("%p", scope), fmt.Sprintf("%p", container))
+		var handler *Handler
+		require.NoError(t, scope.Resolve(&handler))
+		require.Equal(t, fmt.Sprintf("%p", scope), fmt.Sprintf("%p", handler.container))
+		require.NoError(t, c.Resolve(&container))
+		require.Equal(t, fmt.Sprintf("%p", c), fmt.Sprintf("%p", container))
+	})
+
+	t.Run("unnamed scope has empty scope name", func(t *testing.T) {
+		c, err := di.New()
+		require.NoError(t, err)
+		scope, err := c.Scope()
+		require.NoError(t, err)
+		var name di.ScopeName
+		require.NoError(t, scope.Resolve(&name))
+		require.Equal(t, di.ScopeName(""), name)
+		named, err := c.Scope(di.Scope("request"))
+		require.NoError(t, err)
+		require.NoError(t, named.Resolve(&name))
+		require.Equal(t, di.ScopeName("request"), name)
+	})
+}
+
+func TestContainer_Graph(t *testing.T) {
+	t.Run("graph contains provided types and dependencies", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }, di.As(new(http.Handler))),
+			di.Provide(func(handler http.Handler) *http.Server { return &http.Server{Handler: handler} }),
+		)
+		require.NoError(t, err)
+		graph := c.Graph()
+		var mux, server *di.GraphNode
+		for i, node := range graph.Nodes {
+			switch node.ID {
+			case "*http.ServeMux":
+				mux = &graph.Nodes[i]
+			case "*http.Server":
+				server = &graph.Nodes[i]
+			}
+		}
+		require.NotNil(t, mux)
+		require.NotNil(t, server)
+		require.Equal(t, []string{"http.Handler"}, mux.Interfaces)
+		require.Equal(t, "singleton", mux.Lifetime)
+		require.Contains(t, server.Provider, "TestContainer_Graph")
+		require.Contains(t, server.Location, "container_test.go:")
+		require.Contains(t, graph.Edges, di.GraphEdge{From: "*http.Server", To: "*http.ServeMux"})
+	})
+
+	t.Run("groups and di.Inject fields are edges", func(t *testing.T) {
+		type Params struct {
+			di.Inject
+			Servers []*http.Server
+			File    *os.File `di:"optional"`
+		}
+		c, err := di.New(
+			di.Provide(func() *http.Server { return &http.Server{} }, di.Tags{"name": "first"}),
+			di.Provide(func() *http.Server { return &http.Server{} }, di.Tags{"name": "second"}),
+			di.Provide(func(params Params) *http.ServeMux { return &http.ServeMux{} }),
+		)
+		require.NoError(t, err)
+		edges := c.Graph().Edges
+		require.Contains(t, edges, di.GraphEdge{From: "*http.ServeMux", To: "di_test.Params"})
+		require.Contains(t, edges, di.GraphEdge{From: "di_test.Params", To: "*http.Server[name:first]"})
+		require.Contains(t, edges, di.GraphEdge{From: "di_test.Params", To: "*http.Server[name:second]"})
+	})
+
+	t.Run("missing dependency is node with error", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+		)
+		require.NoError(t, err)
+		graph := c.Graph()
+		require.Contains(t, graph.Edges, di.GraphEdge{From: "*http.Server", To: "*http.ServeMux"})
+		for _, node := range graph.Nodes {
+			if node.ID == "*http.ServeMux" {
+				require.Contains(t, node.Error, "not exists in the container")
+			}
+		}
+	})
+
+	t.Run("factory dependency is lazy", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+			di.Provide(func(factory func() *http.ServeMux) *http.Server { return &http.Server{} }),
+		)
+		require.NoError(t, err)
+		require.Contains(t, c.Graph().Edges, di.GraphEdge{From: "*http.Server", To: "*http.ServeMux", Lazy: true})
+	})
+
+	t.Run("graph renders DOT and JSON", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+			di.Provide(func(mux *http.ServeMux, file *os.File) *http.Server { return &http.Server{Handler: mux} }),
+		)
+		require.NoError(t, err)
+		graph := c.Graph()
+		dot := graph.DOT()
+		require.True(t, strings.HasPrefix(dot, "digraph di {\n"))
+		require.Contains(t, dot, "\t\"*http.Server\" -> \"*http.ServeMux\";\n")
+		require.Contains(t, dot, "\t\"*os.File\" [color=red, tooltip=")
+		data, err := graph.JSON()
+		require.NoError(t, err)
+		var decoded di.Graph
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.Equal(t, *graph, decoded)
+	})
+
+	t.Run("optional lazy edge has single style", func(t *testing.T) {
+		graph := &di.Graph{Edges: []di.GraphEdge{
+			{From: "*http.Server", To: "*http.ServeMux", Optional: true, Lazy: true},
+			{From: "*http.Server", To: "*os.File", Lazy: true},
+		}}
+		dot := graph.DOT()
+		require.Contains(t, dot, "\t\"*http.Server\" -> \"*http.ServeMux\" [style=\"dashed,dotted\"];\n")
+		require.Contains(t, dot, "\t\"*http.Server\" -> \"*os.File\" [style=\"dotted\"];\n")
+	})
+}
+
+func TestContainer_Validate(t *testing.T) {
+	t.Run("all missing dependencies are reported", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func(mux *http.ServeMux, file *os.File) *http.Server { return &http.Server{Handler: mux} }),
+			di.Provide(func(conn *net.TCPConn) *net.UDPConn { return &net.UDPConn{} }),
+		)
+		require.NoError(t, err)
+		err = c.Validate()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*http.Server: type *http.ServeMux not exists in the container")
+		require.Contains(t, err.Error(), "*http.Server: type *os.File not exists in the container")
+		require.Contains(t, err.Error(), "*net.UDPConn: type *net.TCPConn not exists in the container")
+	})
+
+	t.Run("multiple definitions are reported", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+			di.Provide(func() *http.ServeMux { return &http.ServeMux{} }),
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+		)
+		require.NoError(t, err)
+		err = c.Validate()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*http.Server: multiple definitions of *http.ServeMux")
+	})
+
+	t.Run("each cycle is reported once with path", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+			di.Provide(func(server *http.Server) *http.ServeMux { return &http.ServeMux{} }),
+			di.Provide(func(conn *net.UDPConn) *net.TCPConn { return &net.TCPConn{} }),
+			di.Provide(func(conn *net.TCPConn) *net.UDPConn { return &net.UDPConn{} }),
+		)
+		require.NoError(t, err)
+		err = c.Validate()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "cycle detected: *http.Server -> *http.ServeMux -> *http.Server")
+		require.Contains(t, err.Error(), "cycle detected: *net.TCPConn -> *net.UDPConn -> *net.TCPConn")
+		require.Len(t, strings.Split(err.Error(), "\n"), 2)
+	})
+
+	t.Run("valid graph is not built", func(t *testing.T) {
+		var calls int
+		c, err := di.New(
+			di.Provide(func() *http.ServeMux {
+				calls++
+				return &http.ServeMux{}
+			}),
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.Validate())
+		require.Equal(t, 0, calls)
+	})
+
+	t.Run("dependencies of scoped and transient types are left to scopes", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func(req *http.Request) *bytes.Buffer { return bytes.NewBufferString(req.URL.Path) }, di.Scoped()),
+			di.Provide(func(req *http.Request) *http.ServeMux { return &http.ServeMux{} }, di.Transient()),
+		)
+		require.NoError(t, err)
+		require.NoError(t, c.Validate())
+		scope, err := c.Scope(di.ProvideValue(newRequest("/users")))
+		require.NoError(t, err)
+		var buf *bytes.Buffer
+		require.NoError(t, scope.Resolve(&buf))
+		require.Equal(t, "/users", buf.String())
+	})
+
+	t.Run("singleton depends on transient with missing dependency", func(t *testing.T) {
+		c, err := di.New(
+			di.Provide(func(req *http.Request) *http.ServeMux { return &http.ServeMux{} }, di.Transient()),
+			di.Provide(func(mux *http.ServeMux) *http.Server { return &http.Server{Handler: mux} }),
+		)
+		require.NoError(t, err)
+		err = c.Validate()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "*http.ServeMux: type *http.Request not exists in the container")
+	})
+}
+
+// newRequest creates GET request with path.
+func newRequest(path string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	return req
+}
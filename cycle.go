@@ -1,7 +1,9 @@
 package di
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 )
 
 const (
@@ -9,12 +11,14 @@ const (
 	permanent = 2
 )
 
-func visit(s schema, node *node, marks map[*node]int) error {
+// visit visits node dependencies in depth. Visited nodes are appended to order after
+// all of their dependencies, so order is a topological order of the graph.
+func visit(s schema, node *node, marks map[*node]int, order *[]*node) error {
 	if marks[node] == permanent {
 		return nil
 	}
 	if marks[node] == temporary {
-		return errCycleDetected // todo: improve message
+		return newCycleError(node)
 	}
 	marks[node] = temporary
 	params, err := node.deps(s)
@@ -22,10 +26,14 @@ func visit(s schema, node *node, marks map[*node]int) error {
 		return fmt.Errorf("%s: %s", node, err)
 	}
 	for _, param := range params {
-		if err := visit(s, param, marks); err != nil {
-			return err
+		s.link(param, node)
+		if err := visit(s, param, marks, order); err != nil {
+			return withCyclePath(node, err)
 		}
 	}
+	if err := checkUnexportedFields(node.rt); err != nil {
+		return fmt.Errorf("%s: %w", node, err)
+	}
 	for _, field := range node.fields() {
 		n, err := s.find(field.rt, field.tags)
 		if err != nil && field.optional {
@@ -34,10 +42,45 @@ func visit(s schema, node *node, marks map[*node]int) error {
 		if err != nil {
 			return fmt.Errorf("%s: %s", node, err)
 		}
-		if err := visit(s, n, marks); err != nil {
-			return err
+		s.link(n, node)
+		if err := visit(s, n, marks, order); err != nil {
+			return withCyclePath(node, err)
 		}
 	}
 	marks[node] = permanent
+	*order = append(*order, node)
 	return nil
 }
+
+// cycleError is an error of dependency cycle. Path starts and ends with the same node.
+type cycleError struct {
+	path   []*node
+	closed bool
+}
+
+func (e *cycleError) Error() string {
+	names := make([]string, 0, len(e.path))
+	for _, n := range e.path {
+		names = append(names, n.String())
+	}
+	return fmt.Sprintf("%s: %s", errCycleDetected, strings.Join(names, " -> "))
+}
+
+func (e *cycleError) Unwrap() error {
+	return errCycleDetected
+}
+
+// newCycleError creates cycle error of node that is visited twice.
+func newCycleError(n *node) *cycleError {
+	return &cycleError{path: []*node{n}}
+}
+
+// withCyclePath prepends node to path of cycle error until the cycle is closed.
+func withCyclePath(n *node, err error) error {
+	var cycle *cycleError
+	if errors.As(err, &cycle) && !cycle.closed {
+		cycle.path = append([]*node{n}, cycle.path...)
+		cycle.closed = n == cycle.path[len(cycle.path)-1]
+	}
+	return err
+}
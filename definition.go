@@ -0,0 +1,133 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Lifetime is a lifetime of type instance.
+type Lifetime int
+
+const (
+	// LifetimeSingleton instance is built once and shared between dependents.
+	LifetimeSingleton Lifetime = iota
+	// LifetimeTransient instance is built on each resolve, see di.Transient().
+	LifetimeTransient
+	// LifetimeScoped instance is built once per scope created by container.Scope(), see
+	// di.Scoped().
+	LifetimeScoped
+)
+
+// String returns lifetime name.
+func (l Lifetime) String() string {
+	switch l {
+	case LifetimeSingleton:
+		return "singleton"
+	case LifetimeTransient:
+		return "transient"
+	case LifetimeScoped:
+		return "scoped"
+	}
+	return "unknown"
+}
+
+// Definition describes type provided to container.
+type Definition struct {
+	// Type is a provided type.
+	Type reflect.Type
+	// Tags are tags of provided type.
+	Tags Tags
+	// Dependencies are types that provided type depends on.
+	Dependencies []Dependency
+	// Lifetime is a lifetime of type instance.
+	Lifetime Lifetime
+	// Module is a name of struct module the type was provided from with di.ProvideStruct().
+	// It is empty for other types.
+	Module string
+}
+
+// Dependency describes type that definition depends on.
+type Dependency struct {
+	// Type is a dependency type.
+	Type reflect.Type
+	// Tags are tags the dependency is found with.
+	Tags Tags
+	// Optional dependency may not exist in container.
+	Optional bool
+}
+
+// definitions returns definitions of schema and its ancestors nodes in registration order.
+func (s *defaultSchema) definitions() []Definition {
+	var nodes []*node
+	s.walk(func(n *node) {
+		nodes = append(nodes, n)
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].seq < nodes[j].seq
+	})
+	definitions := make([]Definition, 0, len(nodes))
+	for _, n := range nodes {
+		definitions = append(definitions, n.definition())
+	}
+	return definitions
+}
+
+// summary returns human-readable description of schema nodes sorted by type name. Each line
+// describes provided type, di.As() interfaces are presented as group memberships.
+func (s *defaultSchema) summary() string {
+	var nodes []*node
+	s.walk(func(n *node) {
+		nodes = append(nodes, n)
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].seq < nodes[j].seq
+	})
+	// the first node of shared instance is provided one, others are its interfaces
+	provided := map[*reflect.Value]*node{}
+	groups := map[*node][]string{}
+	var lines []string
+	for _, n := range nodes {
+		if original, ok := provided[n.rv]; ok {
+			groups[original] = append(groups[original], reflect.SliceOf(n.rt).String())
+			continue
+		}
+		provided[n.rv] = n
+	}
+	for _, n := range provided {
+		def := n.definition()
+		line := fmt.Sprintf("%s %s, dependencies: %d", n, def.Lifetime, len(def.Dependencies))
+		if len(groups[n]) > 0 {
+			line += ", groups: " + strings.Join(groups[n], ", ")
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// definition creates description of node.
+func (n *node) definition() Definition {
+	var deps []Dependency
+	for _, dep := range n.dependencies() {
+		deps = append(deps, Dependency{Type: dep.rt, Tags: dep.tags})
+	}
+	fields := n.fields()
+	indexes := make([]int, 0, len(fields))
+	for index := range fields {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		f := fields[index]
+		deps = append(deps, Dependency{Type: f.rt, Tags: f.tags, Optional: f.optional})
+	}
+	return Definition{
+		Type:         n.rt,
+		Tags:         n.tags,
+		Dependencies: deps,
+		Lifetime:     n.lifetime,
+		Module:       n.module,
+	}
+}
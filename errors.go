@@ -3,11 +3,14 @@ package di
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 var (
 	// ErrTypeNotExists causes when type not found in container.
 	ErrTypeNotExists = errors.New("not exists in the container")
+	// ErrCleanupSkipped causes when cleanup exceeds di.SkipSlow() timeout.
+	ErrCleanupSkipped = errors.New("skipped")
 )
 
 var (
@@ -27,6 +30,32 @@ func knownError(err error) bool {
 	return false
 }
 
+// joinErrors joins errors into one error. It returns nil if there are no errors.
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return multiError(errs)
+}
+
+// multiError is a list of errors.
+type multiError []error
+
+func (e multiError) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e multiError) Unwrap() []error {
+	return e
+}
+
 func errWithStack(err error) error {
 	return fmt.Errorf("%s: %w", stacktrace(1), err)
 }
@@ -0,0 +1,87 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Resolver resolves types and invokes functions with resolved dependencies. Container is
+// a Resolver.
+type Resolver interface {
+	// Has checks that type exists.
+	Has(target Pointer, options ...ResolveOption) (bool, error)
+	// Resolve resolves type and fills target pointer.
+	Resolve(target Pointer, options ...ResolveOption) error
+	// Invoke calls the function with resolved dependencies.
+	Invoke(invocation Invocation, options ...InvokeOption) error
+}
+
+// Federate creates resolver that looks for types in containers in the listed order. A type
+// is resolved from the first container that has it, even if other containers have it too.
+// Dependencies of the type are resolved from the same container.
+//
+//	resolver := di.Federate(host, plugin)
+//	err := resolver.Invoke(func(logger *Logger, storage *PluginStorage) {
+//		// logger is resolved from host, storage is resolved from plugin
+//	})
+func Federate(containers ...*Container) Resolver {
+	return federation(containers)
+}
+
+// federation is a resolver of ordered containers.
+type federation []*Container
+
+func (f federation) Has(target Pointer, options ...ResolveOption) (bool, error) {
+	for _, c := range f {
+		has, err := c.Has(target, options...)
+		if err != nil || has {
+			return has, err
+		}
+	}
+	return false, nil
+}
+
+func (f federation) Resolve(target Pointer, options ...ResolveOption) error {
+	for _, c := range f {
+		has, err := c.Has(target, options...)
+		if err != nil {
+			return err
+		}
+		if has {
+			return c.Resolve(target, options...)
+		}
+	}
+	if target == nil || reflect.ValueOf(target).Kind() != reflect.Ptr {
+		return errWithStack(fmt.Errorf("target must be a pointer, got %s", reflect.TypeOf(target)))
+	}
+	return errWithStack(fmt.Errorf("type %s %w", reflect.TypeOf(target).Elem(), ErrTypeNotExists))
+}
+
+func (f federation) Invoke(invocation Invocation, options ...InvokeOption) error {
+	params := InvokeParams{}
+	for _, opt := range options {
+		opt.apply(&params)
+	}
+	fn, valid := inspectFunction(invocation)
+	if !valid || !validateInvocation(fn) {
+		return errWithStack(fmt.Errorf("%w, got %s", errInvalidInvocationSignature, reflect.TypeOf(invocation)))
+	}
+	var args []reflect.Value
+	for i := 0; i < fn.NumIn(); i++ {
+		in := fn.Type.In(i)
+		if n, ok := bind(in, params.Values); ok {
+			args = append(args, n.compiler.(valueCompiler).rv)
+			continue
+		}
+		target := reflect.New(in)
+		if err := f.Resolve(target.Interface()); err != nil {
+			return err
+		}
+		args = append(args, target.Elem())
+	}
+	res := funcResult(fn.Call(args))
+	if len(res) == 0 {
+		return nil
+	}
+	return res.error(0)
+}
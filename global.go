@@ -0,0 +1,53 @@
+package di
+
+import (
+	"sync"
+)
+
+// globalToken is a di.ProvideOnce() token of global registrations.
+const globalToken = "github.com/defval/di.global"
+
+// global is a registry of globally registered constructors.
+var global struct {
+	mu       sync.Mutex
+	provides []provideOptions
+}
+
+// GlobalRegister registers constructor in package-level registry. Types can register
+// themselves in init() function, like database/sql drivers, and containers import them with
+// di.WithGlobalRegistrations() option:
+//
+//	func init() {
+//		di.GlobalRegister(NewPostgresDriver, di.As(new(Driver)))
+//	}
+//
+// Invalid constructors cause error on import.
+func GlobalRegister(constructor Constructor, options ...ProvideOption) {
+	frame := stacktrace(0)
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	global.provides = append(global.provides, provideOptions{
+		frame,
+		constructor,
+		options,
+	})
+}
+
+// WithGlobalRegistrations returns container option that provides constructors registered with
+// di.GlobalRegister(). Constructors are imported once per container, the option applied again
+// is skipped.
+func WithGlobalRegistrations() Option {
+	return ProvideOnce(globalToken, option(func(c *diopts) {
+		global.mu.Lock()
+		defer global.mu.Unlock()
+		c.provides = append(c.provides, global.provides...)
+	}))
+}
+
+// ResetGlobalRegistrations removes all constructors registered with di.GlobalRegister(). It is
+// useful in tests.
+func ResetGlobalRegistrations() {
+	global.mu.Lock()
+	defer global.mu.Unlock()
+	global.provides = nil
+}
@@ -0,0 +1,269 @@
+package di
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Graph is a dependency graph of container types. It is built from declared dependencies,
+// the types are not built. Use it to inspect wiring of the container or to render
+// architecture diagrams:
+//
+//	graph := container.Graph()
+//	if err := os.WriteFile("di.dot", []byte(graph.DOT()), 0o644); err != nil {
+//		// handle error
+//	}
+type Graph struct {
+	// Nodes are provided types and types they depend on in registration order.
+	Nodes []GraphNode `json:"nodes"`
+	// Edges are dependencies between nodes.
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a type of dependency graph.
+type GraphNode struct {
+	// ID is a unique identifier of node in graph.
+	ID string `json:"id"`
+	// Type is a name of type.
+	Type string `json:"type"`
+	// Tags are tags of provided type.
+	Tags Tags `json:"tags,omitempty"`
+	// Interfaces are interfaces the type is provided as with di.As().
+	Interfaces []string `json:"interfaces,omitempty"`
+	// Provider is a name of constructor function. It is empty for types that are not provided
+	// with constructor.
+	Provider string `json:"provider,omitempty"`
+	// Location is a file and line of constructor function.
+	Location string `json:"location,omitempty"`
+	// Lifetime is a lifetime of type instance.
+	Lifetime string `json:"lifetime,omitempty"`
+	// Error is an error of dependency lookup, like missing type or multiple definitions. The
+	// node with error is not provided.
+	Error string `json:"error,omitempty"`
+}
+
+// GraphEdge is a dependency of one node on another.
+type GraphEdge struct {
+	// From is an ID of dependent node.
+	From string `json:"from"`
+	// To is an ID of dependency node.
+	To string `json:"to"`
+	// Optional dependency may not exist in container.
+	Optional bool `json:"optional,omitempty"`
+	// Lazy dependency is resolved on call of factory function.
+	Lazy bool `json:"lazy,omitempty"`
+}
+
+// DOT renders graph in Graphviz DOT format. Nodes with error are red, optional dependencies
+// are dashed and lazy ones are dotted.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph di {\n")
+	for _, n := range g.Nodes {
+		var attrs []string
+		if n.Provider != "" {
+			attrs = append(attrs, "tooltip="+strconv.Quote(n.Provider+" "+n.Location))
+		}
+		if n.Error != "" {
+			attrs = append(attrs, "color=red", "tooltip="+strconv.Quote(n.Error))
+		}
+		writeDOTStatement(&b, strconv.Quote(n.ID), attrs)
+	}
+	for _, e := range g.Edges {
+		var styles, attrs []string
+		if e.Optional {
+			styles = append(styles, "dashed")
+		}
+		if e.Lazy {
+			styles = append(styles, "dotted")
+		}
+		if len(styles) > 0 {
+			attrs = append(attrs, "style="+strconv.Quote(strings.Join(styles, ",")))
+		}
+		writeDOTStatement(&b, strconv.Quote(e.From)+" -> "+strconv.Quote(e.To), attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// JSON renders graph in JSON format.
+func (g *Graph) JSON() ([]byte, error) {
+	return json.MarshalIndent(g, "", "  ")
+}
+
+// writeDOTStatement writes DOT statement with attributes.
+func writeDOTStatement(b *strings.Builder, statement string, attrs []string) {
+	b.WriteString("\t" + statement)
+	if len(attrs) > 0 {
+		b.WriteString(" [" + strings.Join(attrs, ", ") + "]")
+	}
+	b.WriteString(";\n")
+}
+
+// graph returns dependency graph of schema and its ancestors nodes. Nodes that share instance
+// are presented by the first one, others are its interfaces.
+func (s *defaultSchema) graph() *Graph {
+	var nodes []*node
+	s.walk(func(n *node) {
+		nodes = append(nodes, n)
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].seq < nodes[j].seq
+	})
+	b := &graphBuilder{
+		schema:   s,
+		graph:    &Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}},
+		provided: map[*reflect.Value]string{},
+		indexes:  map[string]int{},
+	}
+	var provided []*node
+	for _, n := range nodes {
+		if id, ok := b.provided[n.rv]; ok {
+			original := &b.graph.Nodes[b.indexes[id]]
+			original.Interfaces = append(original.Interfaces, n.rt.String())
+			continue
+		}
+		b.provided[n.rv] = b.add(n)
+		provided = append(provided, n)
+	}
+	for _, n := range provided {
+		b.link(n)
+	}
+	return b.graph
+}
+
+// graphBuilder builds dependency graph.
+type graphBuilder struct {
+	schema *defaultSchema
+	graph  *Graph
+	// provided are ids of registered nodes by their instance
+	provided map[*reflect.Value]string
+	// indexes are indexes of graph nodes by id
+	indexes map[string]int
+}
+
+// add adds node to graph and returns its id. Nodes of the same type and tags get numeric
+// suffix.
+func (b *graphBuilder) add(n *node) string {
+	id := n.String()
+	for i := 2; ; i++ {
+		if _, ok := b.indexes[id]; !ok {
+			break
+		}
+		id = fmt.Sprintf("%s#%d", n, i)
+	}
+	name, location := provider(n)
+	b.indexes[id] = len(b.graph.Nodes)
+	b.graph.Nodes = append(b.graph.Nodes, GraphNode{
+		ID:       id,
+		Type:     n.rt.String(),
+		Tags:     graphTags(n.tags),
+		Provider: name,
+		Location: location,
+		Lifetime: n.lifetime.String(),
+	})
+	return id
+}
+
+// link adds edges of node dependencies to graph.
+func (b *graphBuilder) link(n *node) {
+	from := b.id(n)
+	fields := n.dependencies()
+	populate := n.fields()
+	indexes := make([]int, 0, len(populate))
+	for index := range populate {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		fields = append(fields, populate[index])
+	}
+	for _, f := range fields {
+		dep, err := b.schema.find(f.rt, f.tags)
+		if err != nil && f.optional {
+			continue
+		}
+		if err != nil {
+			id := fmt.Sprintf("%s%s", f.rt, f.tags)
+			if _, ok := b.indexes[id]; !ok {
+				b.indexes[id] = len(b.graph.Nodes)
+				b.graph.Nodes = append(b.graph.Nodes, GraphNode{
+					ID:    id,
+					Type:  f.rt.String(),
+					Tags:  graphTags(f.tags),
+					Error: err.Error(),
+				})
+			}
+			b.graph.Edges = append(b.graph.Edges, GraphEdge{From: from, To: id, Optional: f.optional})
+			continue
+		}
+		targets, lazy := b.targets(dep)
+		for _, target := range targets {
+			b.graph.Edges = append(b.graph.Edges, GraphEdge{From: from, To: b.id(target), Optional: f.optional, Lazy: lazy})
+		}
+	}
+}
+
+// id returns id of node. Nodes that are not registered, like structs with di.Inject, are added
+// to graph with their dependencies on the first call.
+func (b *graphBuilder) id(n *node) string {
+	if id, ok := b.provided[n.rv]; ok {
+		return id
+	}
+	id := n.String()
+	if _, ok := b.indexes[id]; ok {
+		return id
+	}
+	b.add(n)
+	b.link(n)
+	return id
+}
+
+// targets returns nodes that dependency node stands for: members of groups, bindings of
+// interface pointers and results of factories. Factory results are lazy.
+func (b *graphBuilder) targets(n *node) (targets []*node, lazy bool) {
+	switch compiler := n.compiler.(type) {
+	case *factoryCompiler:
+		result, err := b.schema.find(compiler.rt.Out(0), compiler.tags)
+		if err != nil {
+			return nil, true
+		}
+		targets, _ = b.targets(result)
+		return targets, true
+	case *groupCompiler, *pointerCompiler:
+		deps, _ := n.deps(b.schema)
+		for _, dep := range deps {
+			nodes, _ := b.targets(dep)
+			targets = append(targets, nodes...)
+		}
+		return targets, false
+	}
+	return []*node{n}, false
+}
+
+// graphTags returns tags of graph node, empty tags are omitted.
+func graphTags(tags Tags) Tags {
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+// provider returns name and location of node constructor.
+func provider(n *node) (name string, location string) {
+	compiler, ok := n.compiler.(*constructorCompiler)
+	if !ok {
+		return "", ""
+	}
+	fn := runtime.FuncForPC(compiler.fn.Pointer())
+	if fn == nil {
+		return compiler.fn.Name, ""
+	}
+	file, line := fn.FileLine(fn.Entry())
+	return compiler.fn.Name, fmt.Sprintf("%s:%d", file, line)
+}
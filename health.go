@@ -0,0 +1,86 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+)
+
+// healthTag is a tag key of health check name.
+const healthTag = "health"
+
+// HealthCheck is a health probe. Health checks are collected into group []di.HealthCheck and
+// run by container.HealthCheck(). Provide them with di.ProvideHealthCheck() or with
+// constructors tagged by health check name:
+//
+//	di.Provide(func(db *sql.DB) di.HealthCheck { return db.Ping }, di.Tags{"health": "database"})
+type HealthCheck func() error
+
+// ProvideHealthCheck returns container option that provides health check with name.
+//
+//	di.ProvideHealthCheck("redis", func() error {
+//		return client.Ping(context.Background()).Err()
+//	})
+func ProvideHealthCheck(name string, check func() error) Option {
+	frame := stacktrace(0)
+	return option(func(c *diopts) {
+		c.values = append(c.values, provideValueOptions{
+			frame,
+			HealthCheck(check),
+			[]ProvideOption{Tags{healthTag: name}},
+		})
+	})
+}
+
+// HealthCheck runs all health checks in order they were provided and returns their joined
+// errors, each error is prefixed by name of the health check. It returns nil if there are no
+// health checks.
+//
+//	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+//		if err := container.HealthCheck(); err != nil {
+//			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+//		}
+//	})
+func (c *Container) HealthCheck() error {
+	n, err := c.find(new([]HealthCheck))
+	if errors.Is(err, ErrTypeNotExists) {
+		return nil
+	}
+	if err != nil {
+		return errWithStack(err)
+	}
+	members := []*node{n}
+	if group, ok := n.compiler.(*groupCompiler); ok {
+		members = group.matched
+	}
+	var errs []error
+	for _, member := range members {
+		value, err := member.Value(c.schema)
+		if err != nil {
+			return errWithStack(fmt.Errorf("%s: %w", member, err))
+		}
+		var checks []HealthCheck
+		if member == n {
+			// slice is provided directly
+			checks = value.Interface().([]HealthCheck)
+		} else {
+			checks = []HealthCheck{value.Interface().(HealthCheck)}
+		}
+		for _, check := range checks {
+			if check == nil {
+				continue
+			}
+			if err := check(); err != nil {
+				errs = append(errs, fmt.Errorf("%s health check: %w", healthName(member), err))
+			}
+		}
+	}
+	return joinErrors(errs)
+}
+
+// healthName returns name of health check node.
+func healthName(n *node) string {
+	if name, ok := n.tags[healthTag]; ok {
+		return name
+	}
+	return n.String()
+}
@@ -80,6 +80,10 @@ func parsePopulateFields(rt reflect.Type) map[int]field {
 		}
 		// cur - current field
 		cur := rt.Field(fi)
+		// embedded di.Inject is a marker
+		if cur.Anonymous && cur.Type == injectType {
+			continue
+		}
 		f, valid := inspectStructField(rt, cur)
 		if !valid {
 			continue
@@ -93,6 +97,29 @@ func parsePopulateFields(rt reflect.Type) map[int]field {
 	return fields
 }
 
+// checkUnexportedFields checks that struct rt with di.Inject has no tagged unexported fields, they
+// are meant to be injected but can't be. Both di tag and deprecated tag style are checked,
+// unexported fields without tags are skipped as private state of struct.
+func checkUnexportedFields(rt reflect.Type) error {
+	if !canInject(rt) {
+		return nil
+	}
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.IsExported() || f.Anonymous || f.Tag == "" {
+			continue
+		}
+		if _, injectable := inspectStructField(rt, f); !injectable {
+			continue
+		}
+		return fmt.Errorf("cannot inject unexported field %s of %s", f.Name, rt)
+	}
+	return nil
+}
+
 // inspectStructField parses struct field
 func inspectStructField(rt reflect.Type, f reflect.StructField) (field, bool) {
 
@@ -222,3 +249,5 @@ func inspectStructFieldDeprecated(f reflect.StructField) (field, bool) {
 }
 
 var injectableInterface = reflect.TypeOf(new(injectable)).Elem()
+
+var injectType = reflect.TypeOf(Inject{})
@@ -3,7 +3,9 @@ package di
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"runtime"
+	"strings"
 )
 
 // Func is a function description.
@@ -20,9 +22,25 @@ func isError(typ reflect.Type) bool {
 	return typ.Implements(errorInterface)
 }
 
-// isCleanup checks that typ have cleanup signature.
+// isCleanup checks that typ have cleanup signature: func(), func() error, func(CleanupResolver)
+// or func(CleanupResolver) error.
 func isCleanup(typ reflect.Type) bool {
-	return typ.Kind() == reflect.Func && typ.NumIn() == 0 && typ.NumOut() == 0
+	if typ.Kind() != reflect.Func || typ.NumIn() > 1 {
+		return false
+	}
+	if typ.NumIn() == 1 && typ.In(0) != cleanupResolverType {
+		return false
+	}
+	return typ.NumOut() == 0 || typ.NumOut() == 1 && typ.Out(0) == errorInterface
+}
+
+// isNil checks that rv is nil value of nillable kind.
+func isNil(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	}
+	return false
 }
 
 // InspectFunc inspects function.
@@ -40,6 +58,15 @@ func inspectFunction(fn interface{}) (function, bool) {
 	}, true
 }
 
+// closureName matches names of function literals, like main.main.func1.2.
+var closureName = regexp.MustCompile(`\.func\d+(\.\d+)*$`)
+
+// isClosure checks that function is a function literal or a method value. Closures of the same
+// literal or method have the same code pointer, while their captured variables may differ.
+func isClosure(fn function) bool {
+	return closureName.MatchString(fn.Name) || strings.HasSuffix(fn.Name, "-fm")
+}
+
 // Interface is a interface description.
 type link struct {
 	Name string
@@ -0,0 +1,62 @@
+package di
+
+import (
+	"reflect"
+	"time"
+)
+
+// ConstructInfo describes type that is being constructed.
+type ConstructInfo struct {
+	// Type is a constructed type.
+	Type reflect.Type
+	// Tags are tags of constructed type.
+	Tags Tags
+	// Labels are metadata of provided type set by di.WithLabels() option.
+	Labels map[string]string
+}
+
+// ConstructFunc constructs type instance.
+type ConstructFunc func(info ConstructInfo) (reflect.Value, error)
+
+// Interceptor wraps construction of every type. It must call next to construct instance.
+type Interceptor func(next ConstructFunc) ConstructFunc
+
+// ResolveEvent describes construction of type instance.
+type ResolveEvent struct {
+	// Type is a constructed type.
+	Type reflect.Type
+	// Tags are tags of constructed type.
+	Tags Tags
+	// Labels are metadata of provided type set by di.WithLabels() option.
+	Labels map[string]string
+	// Duration is a construction duration, dependencies construction is not included.
+	Duration time.Duration
+	// Err is a construction error.
+	Err error
+}
+
+// observe creates interceptor that reports construction events to fn.
+func observe(fn func(event ResolveEvent)) Interceptor {
+	return func(next ConstructFunc) ConstructFunc {
+		return func(info ConstructInfo) (reflect.Value, error) {
+			start := time.Now()
+			rv, err := next(info)
+			fn(ResolveEvent{
+				Type:     info.Type,
+				Tags:     info.Tags,
+				Labels:   info.Labels,
+				Duration: time.Since(start),
+				Err:      err,
+			})
+			return rv, err
+		}
+	}
+}
+
+// intercept wraps construct function into interceptors. The first interceptor is the outermost.
+func intercept(interceptors []Interceptor, construct ConstructFunc) ConstructFunc {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		construct = interceptors[i](construct)
+	}
+	return construct
+}
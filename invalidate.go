@@ -0,0 +1,88 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// link records that dependent node uses dependency. Groups, factories, pointers to interfaces
+// and not cached di.Inject structs are created on each find, so their dependencies are linked
+// to dependent directly.
+func (s *defaultSchema) link(dependency, dependent *node) {
+	if s.ephemeral(dependent) {
+		return
+	}
+	if s.ephemeral(dependency) {
+		nodes, _ := dependency.deps(s)
+		// fields of di.Inject struct are its dependencies
+		for _, field := range dependency.fields() {
+			if n, err := s.find(field.rt, field.tags); err == nil {
+				nodes = append(nodes, n)
+			}
+		}
+		for _, n := range nodes {
+			s.link(n, dependent)
+		}
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dependents[dependency.rv] == nil {
+		s.dependents[dependency.rv] = map[*node]bool{}
+	}
+	s.dependents[dependency.rv][dependent] = true
+}
+
+// ephemeral checks that node is created on each find and is not registered in schema.
+func (s *defaultSchema) ephemeral(n *node) bool {
+	switch n.compiler.(type) {
+	case *groupCompiler, *pointerCompiler, *factoryCompiler:
+		return true
+	case *typeCompiler:
+		return s.noInjectCache
+	}
+	return false
+}
+
+// invalidate clears cached instances of volatile nodes of type t and all of their dependents.
+func (s *defaultSchema) invalidate(t reflect.Type) error {
+	nodes, _ := s.list(t)
+	var volatile []*node
+	for _, n := range nodes {
+		if n.volatile {
+			volatile = append(volatile, n)
+		}
+	}
+	if len(volatile) == 0 {
+		return fmt.Errorf("type %s is not volatile, use di.Volatile() provide option", t)
+	}
+	cleared := map[*reflect.Value]bool{}
+	for _, n := range volatile {
+		s.clear(n, cleared)
+	}
+	return nil
+}
+
+// clear clears cached instance of node and its dependents.
+func (s *defaultSchema) clear(n *node, cleared map[*reflect.Value]bool) {
+	if cleared[n.rv] {
+		return
+	}
+	cleared[n.rv] = true
+	n.mu.Lock()
+	*n.rv = reflect.Value{}
+	if n.raw != nil {
+		*n.raw = reflect.Value{}
+	}
+	n.mu.Unlock()
+	tracer.Trace("Invalidated %s", n)
+	s.mu.Lock()
+	var dependents []*node
+	for dependent := range s.dependents[n.rv] {
+		dependents = append(dependents, dependent)
+	}
+	s.mu.Unlock()
+	for _, dependent := range dependents {
+		s.clear(dependent, cleared)
+	}
+}
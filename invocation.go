@@ -1,5 +1,10 @@
 package di
 
+import (
+	"reflect"
+	"sync"
+)
+
 // validateInvocation validates function.
 func validateInvocation(fn function) bool {
 	if fn.NumOut() == 0 {
@@ -11,10 +16,15 @@ func validateInvocation(fn function) bool {
 	return false
 }
 
-// parseInvocationParameters parses invocation and returns slice of nodes.
-func parseInvocationParameters(fn function, s schema) (params []*node, err error) {
+// parseInvocationParameters parses invocation and returns slice of nodes. Bound values
+// are used before types of schema.
+func parseInvocationParameters(fn function, s schema, values []Value) (params []*node, err error) {
 	for i := 0; i < fn.NumIn(); i++ {
 		in := fn.Type.In(i)
+		if n, ok := bind(in, values); ok {
+			params = append(params, n)
+			continue
+		}
 		node, err := s.find(in, Tags{})
 		if err != nil {
 			return nil, err
@@ -23,3 +33,31 @@ func parseInvocationParameters(fn function, s schema) (params []*node, err error
 	}
 	return params, nil
 }
+
+// bind creates node of the value that can be injected as t. The value of type t is preferred
+// to value that is assignable to t.
+func bind(t reflect.Type, values []Value) (*node, bool) {
+	var bound reflect.Value
+	for _, value := range values {
+		rv := reflect.ValueOf(value)
+		if rv.Type() == t {
+			bound = rv
+			break
+		}
+		if !bound.IsValid() && rv.Type().AssignableTo(t) {
+			bound = rv
+		}
+	}
+	if !bound.IsValid() {
+		return nil, false
+	}
+	rv := reflect.New(t).Elem()
+	rv.Set(bound)
+	return &node{
+		compiler: valueCompiler{rv: rv},
+		rt:       t,
+		tags:     Tags{},
+		rv:       new(reflect.Value),
+		mu:       new(sync.Mutex),
+	}, true
+}
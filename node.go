@@ -3,17 +3,19 @@ package di
 import (
 	"fmt"
 	"reflect"
+	"sync"
+	"sync/atomic"
 )
 
 // newConstructorNode
 func newConstructorNode(ctor interface{}) (*node, error) {
 	f, valid := inspectFunction(ctor)
 	if !valid {
-		return nil, fmt.Errorf("invalid constructor signature, got %s", reflect.TypeOf(ctor))
+		return nil, fmt.Errorf("invalid constructor signature, got %s: constructor must be a function", reflect.TypeOf(ctor))
 	}
-	cmp, ok := newConstructorCompiler(f)
-	if !ok {
-		return nil, fmt.Errorf("invalid constructor signature, got %s", f.Type)
+	cmp, err := newConstructorCompiler(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid constructor signature, got %s: %w", f.Type, err)
 	}
 	// result type
 	rt := f.Out(0)
@@ -34,6 +36,8 @@ func newConstructorNode(ctor interface{}) (*node, error) {
 	}
 	return &node{
 		rv:       new(reflect.Value),
+		raw:      new(reflect.Value),
+		mu:       new(sync.Mutex),
 		rt:       rt,
 		tags:     tags,
 		compiler: cmp,
@@ -48,8 +52,54 @@ type node struct {
 	// rv value can be shared between nodes
 	// initializing node always need to allocate memory for rv
 	rv *reflect.Value
+	// raw is a value built without decorators, it is shared between nodes like rv
+	raw *reflect.Value
+	// mu guards building of rv and raw, it is shared between nodes like rv
+	mu *sync.Mutex
 	// decorators
 	decorators []Decorator
+	// seq is a registration sequence number
+	seq uint64
+	// eager nodes are built on container build
+	eager bool
+	// condition of group membership, it is shared between nodes like rv
+	condition *condition
+	// volatile node instance can be invalidated
+	volatile bool
+	// module is a name of struct module the node was provided from
+	module string
+	// labels are metadata of node
+	labels map[string]string
+	// lockThread node is compiled on goroutine locked to OS thread
+	lockThread bool
+	// weight is a weight of node in weighted selection
+	weight int
+	// phase is a construction phase of node
+	phase *phase
+	// lifetime is a lifetime of node instance
+	lifetime Lifetime
+	// options describes provide options of constructor node to compare identical providers
+	options string
+}
+
+// share returns node of type rt with tags that shares instance and provide options with node.
+func (n *node) share(rt reflect.Type, tags Tags) *node {
+	return &node{
+		rv:         n.rv,
+		raw:        n.raw,
+		mu:         n.mu,
+		rt:         rt,
+		tags:       tags,
+		compiler:   n.compiler,
+		decorators: n.decorators,
+		condition:  n.condition,
+		volatile:   n.volatile,
+		module:     n.module,
+		labels:     n.labels,
+		lockThread: n.lockThread,
+		weight:     n.weight,
+		lifetime:   n.lifetime,
+	}
 }
 
 // String is a string representation of node.
@@ -59,43 +109,161 @@ func (n *node) String() string {
 
 // Value returns value of node.
 func (n *node) Value(s schema) (reflect.Value, error) {
-	if n.rv.IsValid() {
-		return *n.rv, nil
+	return n.value(s, true)
+}
+
+// Undecorated returns value of node that was built without decorators. Undecorated value
+// is cached separately of decorated one because decorators can modify instance.
+func (n *node) Undecorated(s schema) (reflect.Value, error) {
+	if len(n.decorators) == 0 || n.raw == nil {
+		return n.Value(s)
+	}
+	return n.value(s, false)
+}
+
+// value returns cached or builds new value of node. Transient instances are not cached, scoped
+// instances are cached by scope and singletons are built by the schema that is not a scope.
+func (n *node) value(s schema, decorate bool) (reflect.Value, error) {
+	// factory called by constructor would wait for the node it builds
+	if s.building(n) {
+		return reflect.Value{}, fmt.Errorf("%s is under construction: %w", n, errCycleDetected)
+	}
+	if n.lifetime == LifetimeTransient {
+		s.record(false)
+		return n.build(s.traced(n, false), decorate)
+	}
+	if n.lifetime == LifetimeSingleton {
+		s = s.singletons(n)
 	}
+	rv, raw, mu, err := n.storage(s)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	cache := rv
+	if !decorate {
+		cache = raw
+	}
+	if cache.IsValid() {
+		s.record(true)
+		s.traced(n, true)
+		return *cache, nil
+	}
+	s.record(false)
+	value, err := n.build(s.traced(n, false), decorate)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	*cache = value
+	return *cache, nil
+}
+
+// storage returns storage of node instance in schema.
+func (n *node) storage(s schema) (rv, raw *reflect.Value, mu *sync.Mutex, err error) {
+	if n.lifetime != LifetimeScoped {
+		return n.rv, n.raw, n.mu, nil
+	}
+	instance, err := s.scoped(n)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return instance.rv, instance.raw, instance.mu, nil
+}
+
+// build builds new value of node.
+func (n *node) build(s schema, decorate bool) (reflect.Value, error) {
+	// dependencies are built with node schema too, so factories they get know that node is
+	// under construction
+	ns := newNodeSchema(s, n)
+	defer ns.constructing.Store(false)
 	nodes, _ := n.deps(s) // todo: error skipped, prepare already check dependency graph
 	var dependencies []reflect.Value
 	for _, node := range nodes {
-		v, err := node.Value(s)
+		v, err := node.Value(ns)
 		if err != nil {
 			return reflect.Value{}, fmt.Errorf("%s: %w", node, err)
 		}
 		dependencies = append(dependencies, v)
 	}
-	rv, err := n.compile(dependencies, s)
+	construct := intercept(s.interceptors(), func(info ConstructInfo) (reflect.Value, error) {
+		if n.lockThread {
+			return n.compileLocked(dependencies, ns)
+		}
+		return n.compile(dependencies, ns)
+	})
+	rv, err := construct(ConstructInfo{Type: n.rt, Tags: n.tags, Labels: n.labels})
 	if err != nil {
 		tracer.Trace("%s: %s", n.String(), err)
 		return reflect.Value{}, err
 	}
+	if !rv.IsValid() {
+		return reflect.Value{}, fmt.Errorf("%s constructed invalid value", n)
+	}
+	if s.disallowNil() && isNil(rv) {
+		return reflect.Value{}, fmt.Errorf("%s is nil, nil instances are disallowed", n)
+	}
 	// if result value not addr, create pointer for it
 	if !rv.CanAddr() {
 		addr := reflect.New(rv.Type())
 		addr.Elem().Set(rv)
 		rv = addr.Elem()
 	}
-	if err := populate(s, rv); err != nil {
+	if err := populate(ns, rv); err != nil {
 		tracer.Trace("%s: %s", n.String(), err)
 		return reflect.Value{}, err
 	}
 	for _, decorator := range n.decorators {
+		if !decorate {
+			continue
+		}
 		tracer.Trace("Run resolve decorator for %s", n.String())
 		if err := decorator(rv.Interface()); err != nil {
 			tracer.Trace("Decorator error %s", err)
 			return reflect.Value{}, err
 		}
 	}
-	*n.rv = rv
 	tracer.Trace("Resolved %s", n.String())
-	return *n.rv, nil
+	return rv, nil
+}
+
+// nodeSchema is a schema that is used during node compilation. It binds registered
+// cleanups to the node.
+type nodeSchema struct {
+	schema
+	node *node
+	// constructing is true until constructor of node returns
+	constructing *atomic.Bool
+}
+
+// newNodeSchema creates schema of node that is constructed.
+func newNodeSchema(s schema, n *node) nodeSchema {
+	constructing := new(atomic.Bool)
+	constructing.Store(true)
+	return nodeSchema{schema: s, node: n, constructing: constructing}
+}
+
+func (s nodeSchema) cleanup(cleanup *destructor) {
+	// node schemas are nested when factory builds node during construction of another one
+	if cleanup.node == nil {
+		cleanup.node = s.node
+	}
+	s.schema.cleanup(cleanup)
+}
+
+func (s nodeSchema) building(n *node) bool {
+	if s.node.rv == n.rv && s.constructing.Load() {
+		return true
+	}
+	return s.schema.building(n)
+}
+
+func (s nodeSchema) singletons(n *node) schema {
+	return nodeSchema{schema: s.schema.singletons(n), node: s.node, constructing: s.constructing}
+}
+
+func (s nodeSchema) traced(n *node, cached bool) schema {
+	return nodeSchema{schema: s.schema.traced(n, cached), node: s.node, constructing: s.constructing}
 }
 
 func (n *node) fields() map[int]field {
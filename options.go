@@ -1,5 +1,10 @@
 package di
 
+import (
+	"fmt"
+	"reflect"
+)
+
 // Option is a functional option that configures container. If you don't know about functional
 // options, see https://dave.cheney.net/2014/10/17/functional-options-for-friendly-apis.
 // Below presented all possible options with their description:
@@ -26,6 +31,54 @@ func Provide(constructor Constructor, options ...ProvideOption) Option {
 	})
 }
 
+// ProvideGroup returns container option that provides constructors as members of group []T.
+// Each constructor result must implement T, if T is an interface, or be T. Members are
+// resolved in the order of constructors and can be mixed with members provided in other way.
+//
+//	di.ProvideGroup[http.Handler](NewUsersHandler, NewOrdersHandler)
+func ProvideGroup[T any](constructors ...Constructor) Option {
+	frame := stacktrace(0)
+	return option(func(c *diopts) {
+		for _, constructor := range constructors {
+			c.provides = append(c.provides, provideOptions{
+				frame,
+				constructor,
+				[]ProvideOption{InGroup(new([]T))},
+			})
+		}
+	})
+}
+
+// ProvideSection returns container option that provides configuration section S derived from
+// parent configuration P. If the section has Validate() error method, it is called after the
+// section is derived and its error fails the resolve.
+//
+//	type AppConfig struct {
+//		DB DBConfig
+//	}
+//
+//	di.ProvideValue(config),
+//	di.ProvideSection[DBConfig](func(config AppConfig) DBConfig { return config.DB }),
+func ProvideSection[S any, P any](section func(P) S, options ...ProvideOption) Option {
+	frame := stacktrace(0)
+	constructor := func(parent P) (S, error) {
+		result := section(parent)
+		if validator, ok := any(result).(interface{ Validate() error }); ok {
+			if err := validator.Validate(); err != nil {
+				return result, fmt.Errorf("invalid section %s: %w", reflect.TypeOf(&result).Elem(), err)
+			}
+		}
+		return result, nil
+	}
+	return option(func(c *diopts) {
+		c.provides = append(c.provides, provideOptions{
+			frame,
+			constructor,
+			options,
+		})
+	})
+}
+
 // ProvideValue provides value as is.
 func ProvideValue(value Value, options ...ProvideOption) Option {
 	frame := stacktrace(0)
@@ -38,6 +91,55 @@ func ProvideValue(value Value, options ...ProvideOption) Option {
 	})
 }
 
+// ProvideNamedValue provides value as is with name tag. It is a short way to provide primitive
+// values that share Go type, like configuration strings or durations:
+//
+//	di.ProvideNamedValue("read-timeout", 5*time.Second)
+//	di.ProvideNamedValue("write-timeout", 10*time.Second)
+//
+// Inject named values into struct fields with name tag:
+//
+//	type Timeouts struct {
+//		di.Inject
+//		Read  time.Duration `di:"name=read-timeout"`
+//		Write time.Duration `di:"name=write-timeout"`
+//	}
+func ProvideNamedValue(name string, value Value, options ...ProvideOption) Option {
+	frame := stacktrace(0)
+	return option(func(c *diopts) {
+		c.values = append(c.values, provideValueOptions{
+			frame,
+			value,
+			append(options, Tags{"name": name}),
+		})
+	})
+}
+
+// ProvideStruct returns container option that provides constructors from module struct fields.
+// Each exported not nil field of the struct must be a constructor, fields with di tags are provided
+// with these tags. Use di:"skip" tag to skip field.
+//
+//	type DatabaseModule struct {
+//		Primary func() (*sql.DB, error) `di:"name=primary"`
+//		Replica func() (*sql.DB, error) `di:"name=replica"`
+//	}
+//	container, err := di.New(
+//		di.ProvideStruct(DatabaseModule{
+//			Primary: NewPrimary,
+//			Replica: NewReplica,
+//		}),
+//	)
+func ProvideStruct(module interface{}, options ...ProvideOption) Option {
+	frame := stacktrace(0)
+	return option(func(c *diopts) {
+		c.structs = append(c.structs, provideStructOptions{
+			frame,
+			module,
+			options,
+		})
+	})
+}
+
 // Constructor is a function with follow signature:
 //
 //	func NewHTTPServer(addr string, handler http.Handler) (server *http.Server, cleanup func(), err error) {
@@ -54,6 +156,7 @@ func ProvideValue(value Value, options ...ProvideOption) Option {
 // is a dependencies. They will be resolved automatically when someone needs a server. Constructor may have unlimited
 // count of dependencies, but note that container should know how build each of them.
 // Second result of this function is a optional cleanup callback. It describes that container will do on shutdown.
+// Cleanup callback may return an error: func() error.
 // Third result is a optional error. Sometimes our types cannot be constructed.
 type Constructor interface{}
 
@@ -143,6 +246,118 @@ func Decorate(decorators ...Decorator) ProvideOption {
 	})
 }
 
+// Eager modifies Provide() behavior. The type will be built on container build instead of
+// lazy on-demand construction. Eager types are built in dependency order, so dependencies
+// shared between them are built once.
+func Eager() ProvideOption {
+	return provideOption(func(params *ProvideParams) {
+		params.Eager = true
+	})
+}
+
+// Volatile modifies Provide() behavior. The instance of volatile type can be invalidated with
+// container.Invalidate(). It is useful for types that must be rebuilt on changes, like configuration
+// on hot reload.
+func Volatile() ProvideOption {
+	return provideOption(func(params *ProvideParams) {
+		params.Volatile = true
+	})
+}
+
+// Transient modifies Provide() behavior. The type instance is built on each resolve and
+// injection instead of being cached. Cleanups of transient instances are called on cleanup of
+// the container that built them.
+func Transient() ProvideOption {
+	return provideOption(func(params *ProvideParams) {
+		params.Lifetime = LifetimeTransient
+	})
+}
+
+// Scoped modifies Provide() behavior. The type instance is built once per scope created by
+// container.Scope() and its cleanup is called on scope cleanup. Scoped types can't be resolved
+// from the container itself and can't be dependencies of singletons.
+//
+//	di.Provide(NewTransaction, di.Scoped())
+func Scoped() ProvideOption {
+	return provideOption(func(params *ProvideParams) {
+		params.Lifetime = LifetimeScoped
+	})
+}
+
+// GroupMemberIf modifies Provide() behavior. The type will be a member of groups only if cond
+// returns true. The condition is evaluated once on the first group resolve. The type can still be
+// resolved directly.
+//
+//	di.Provide(NewMetricsPlugin, di.As(new(Plugin)), di.GroupMemberIf(func() bool {
+//		return config.MetricsEnabled
+//	}))
+func GroupMemberIf(cond func() bool) ProvideOption {
+	return provideOption(func(params *ProvideParams) {
+		params.Condition = cond
+	})
+}
+
+// WithLabels modifies Provide() behavior. Labels are metadata of provided type that are passed
+// into di.OnResolve() observer and interceptors, like tracing span attributes.
+//
+//	di.Provide(NewAuthService, di.WithLabels(map[string]string{"component": "auth"}))
+func WithLabels(labels map[string]string) ProvideOption {
+	return provideOption(func(params *ProvideParams) {
+		if params.Labels == nil {
+			params.Labels = map[string]string{}
+		}
+		for k, v := range labels {
+			params.Labels[k] = v
+		}
+	})
+}
+
+// LockOSThread modifies Provide() behavior. The constructor is called on a goroutine locked to
+// OS thread with runtime.LockOSThread(), as some libraries like GUI or CGo bindings require.
+// The cleanup of the constructor, if any, is called on the same thread. Decorators and field
+// injection are not run on the thread. The instance is cached as usual, calling its methods on
+// the right thread is up to the caller.
+//
+//	di.Provide(NewWindow, di.LockOSThread())
+func LockOSThread() ProvideOption {
+	return provideOption(func(params *ProvideParams) {
+		params.LockThread = true
+	})
+}
+
+// Weight modifies Provide() behavior. The weight is a relative probability of the type to be
+// selected on resolve with di.Weighted() option. Types without weight have weight 1.
+//
+//	di.Provide(NewHandler, di.As(new(Handler)), di.Weight(90)),
+//	di.Provide(NewCanaryHandler, di.As(new(Handler)), di.Weight(10)),
+func Weight(weight int) ProvideOption {
+	return provideOption(func(params *ProvideParams) {
+		params.Weight = weight
+	})
+}
+
+// Phase modifies Provide() behavior. The type is built by container.BuildPhased() in phase with
+// name, phases are built in ascending order.
+//
+//	di.Provide(NewDatabase, di.Phase("infra", 1))
+func Phase(name string, order int) ProvideOption {
+	return provideOption(func(params *ProvideParams) {
+		params.Phase = name
+		params.PhaseOrder = order
+	})
+}
+
+// InGroup modifies Provide() behavior. The type will be a member of group, group is a pointer
+// to slice, like new([]http.Handler). Container checks on provide that the type can be
+// appended to the group.
+//
+//	di.Provide(NewMetricsPlugin, di.InGroup(new([]Plugin)))
+func InGroup(group Pointer) ProvideOption {
+	return provideOption(func(params *ProvideParams) {
+		params.Groups = append(params.Groups, group)
+	})
+}
+
 // Resolve returns container options that resolves type into target. All resolves will be done on compile stage
 // after call invokes.
 func Resolve(target Pointer, options ...ResolveOption) Option {
@@ -195,12 +410,120 @@ func Options(options ...Option) Option {
 	})
 }
 
+// ProvideOnce returns container option that applies options only the first time the token is
+// seen by container, in di.New() or container.Apply(). It makes module inclusion idempotent:
+//
+//	func DatabaseModule() di.Option {
+//		return di.ProvideOnce("database", di.Options(
+//			di.Provide(NewDatabase),
+//			di.Provide(NewMigrator),
+//		))
+//	}
+func ProvideOnce(token string, options ...Option) Option {
+	return option(func(c *diopts) {
+		if c.tokens == nil {
+			c.tokens = map[string]bool{}
+		}
+		if c.tokens[token] {
+			return
+		}
+		c.tokens[token] = true
+		for _, opt := range options {
+			opt.apply(c)
+		}
+	})
+}
+
+// GroupDefaults returns container option that applies options and merges tags into tags of each
+// provided type, so group members don't repeat common tags. Tags of the member override
+// inherited ones on conflict:
+//
+//	di.GroupDefaults(di.Tags{"layer": "http"},
+//		di.Provide(NewUserHandler, di.As(new(http.Handler))),
+//		di.Provide(NewAuthHandler, di.As(new(http.Handler)), di.Tags{"layer": "auth"}),
+//	)
+func GroupDefaults(tags Tags, options ...Option) Option {
+	inherit := func(options []ProvideOption) []ProvideOption {
+		return append([]ProvideOption{tags}, options...)
+	}
+	return option(func(c *diopts) {
+		provides, values, structs := len(c.provides), len(c.values), len(c.structs)
+		for _, opt := range options {
+			opt.apply(c)
+		}
+		for i := provides; i < len(c.provides); i++ {
+			c.provides[i].options = inherit(c.provides[i].options)
+		}
+		for i := values; i < len(c.values); i++ {
+			c.values[i].options = inherit(c.values[i].options)
+		}
+		for i := structs; i < len(c.structs); i++ {
+			c.structs[i].options = inherit(c.structs[i].options)
+		}
+	})
+}
+
+// ProvideLater returns container option that calls fn after all provides of di.New() or
+// container.Apply(), before eager types are built and invocations are called. The function can
+// provide types depending on values of other types, like configuration:
+//
+//	di.ProvideLater(func(c *di.Container) error {
+//		var config *Config
+//		if err := c.Resolve(&config); err != nil {
+//			return err
+//		}
+//		if config.Cache == "redis" {
+//			return c.Provide(NewRedisCache, di.As(new(Cache)))
+//		}
+//		return c.Provide(NewMemoryCache, di.As(new(Cache)))
+//	})
+//
+// Functions are called in order of options. Types resolved by the function are built and
+// cached: they don't see types provided after the resolve, so resolve only what the
+// registration decision needs.
+func ProvideLater(fn func(c *Container) error) Option {
+	frame := stacktrace(0)
+	return option(func(c *diopts) {
+		c.laters = append(c.laters, provideLaterOptions{
+			frame,
+			fn,
+		})
+	})
+}
+
+// OnResolve returns container option that calls fn after construction of every type instance.
+//
+//	di.OnResolve(func(event di.ResolveEvent) {
+//		log.Printf("%s built in %s", event.Type, event.Duration)
+//	})
+func OnResolve(fn func(event ResolveEvent)) Option {
+	return option(func(c *diopts) {
+		c.interceptors = append(c.interceptors, observe(fn))
+	})
+}
+
 // ProvideParams is a Provide() method options. Name is a unique identifier of type instance. Provider is a constructor
 // function. Interfaces is a interface that implements a provider result type.
 type ProvideParams struct {
 	Tags       Tags
 	Interfaces []Interface
 	Decorators []Decorator
+	Eager      bool
+	Condition  func() bool
+	Volatile   bool
+	Groups     []Pointer
+	Labels     map[string]string
+	LockThread bool
+	// Weight is a weight of type in di.Weighted() selection, zero means weight 1
+	Weight int
+	// Lifetime is a lifetime of type instance
+	Lifetime Lifetime
+	// Phase is a name of construction phase
+	Phase string
+	// PhaseOrder is an order of construction phase
+	PhaseOrder int
+	// module is set by di.ProvideStruct()
+	module string
 }
 
 func (p ProvideParams) applyProvide(params *ProvideParams) {
@@ -216,12 +539,35 @@ type InvokeOption interface {
 type InvokeParams struct {
 	// The function
 	Fn interface{}
+	// Values are injected into invocation function by type
+	Values []Value
+	// trace is filled by dependencies of invocation
+	trace *Trace
 }
 
 func (p InvokeParams) apply(params *InvokeParams) {
 	*params = p
 }
 
+// With modifies Invoke() behavior. The value is injected into invocation function parameter of
+// the same type, or interface parameter it implements, instead of the type from container. The
+// value is not provided to container and is not injected into dependencies of the invocation.
+//
+//	err := container.Invoke(func(req *http.Request, handler *Handler) error {
+//		return handler.Handle(req)
+//	}, di.With(req))
+func With(value Value) InvokeOption {
+	return invokeOption(func(params *InvokeParams) {
+		params.Values = append(params.Values, value)
+	})
+}
+
+type invokeOption func(params *InvokeParams)
+
+func (o invokeOption) apply(params *InvokeParams) {
+	o(params)
+}
+
 // ResolveOption is a functional option interface that modify resolve behaviour.
 type ResolveOption interface {
 	applyResolve(params *ResolveParams)
@@ -239,15 +585,245 @@ func Name(name string) ResolveOption {
 	})
 }
 
+// Undecorated modifies Resolve() behavior. It resolves instance that was built without
+// decorators of di.Decorate() option. The undecorated instance is a separate singleton:
+// the constructor is called once more and the instance is cached independently of the
+// decorated one. Dependencies of undecorated instance are still decorated. Values provided
+// with ProvideValue() are shared between decorated and undecorated forms.
+func Undecorated() ResolveOption {
+	return resolveOption(func(params *ResolveParams) {
+		params.Undecorated = true
+	})
+}
+
+// ConcatGroups modifies Resolve() behavior. It assembles group from sub-groups matched by
+// tags and appends them in the listed order. Type that matches several sub-groups is appended
+// once, in the first of them.
+//
+//	var middlewares []Middleware
+//	err := container.Resolve(&middlewares, di.ConcatGroups(
+//		di.Tags{"phase": "pre"},
+//		di.Tags{"phase": "post"},
+//	))
+func ConcatGroups(groups ...Tags) ResolveOption {
+	return resolveOption(func(params *ResolveParams) {
+		params.Groups = append(params.Groups, groups...)
+	})
+}
+
+// NoInjectCache returns container option that disables caching of di.Inject struct types that
+// are not provided. By default, the container creates such type on first find and keeps it as
+// a singleton. With the option, each find creates the type anew, so every resolve builds new
+// struct instance.
+func NoInjectCache() Option {
+	return option(func(c *diopts) {
+		c.noInjectCache = true
+	})
+}
+
+// RemoveOnNil returns container option that makes nil constructor remove provided types of its
+// result type matching provide tags, including their di.As() interfaces. It allows a later module
+// to disable a provider of an earlier one. Without the option nil constructor causes error, so
+// accidental nils are not silently treated as removal.
+//
+//	var NewMetrics func() *Metrics // nil disables metrics
+//	if config.MetricsEnabled {
+//		NewMetrics = metrics.New
+//	}
+//	container, err := di.New(
+//		di.RemoveOnNil(),
+//		di.Provide(metrics.New),
+//		di.Provide(NewMetrics),
+//	)
+func RemoveOnNil() Option {
+	return option(func(c *diopts) {
+		c.removeOnNil = true
+	})
+}
+
+// AllowLateRegistration returns container option that allows to provide types after any type was
+// resolved. By default, it causes error, because instances that are already built don't use
+// types provided later, like groups that were resolved before. Types provided by
+// di.ProvideLater() and container.SetProviders() are allowed anyway.
+func AllowLateRegistration() Option {
+	return option(func(c *diopts) {
+		c.lateRegistration = true
+	})
+}
+
+// FailFast returns container option that validates the whole graph on build, before eager types
+// are built and invocations are called. Missing dependencies of all types are returned joined,
+// cycles are reported after them. Dependencies of scoped and transient types may be provided by
+// scopes, so they are not required. By default, the graph is validated on resolve of each type,
+// see also container.Validate().
+//
+//	container, err := di.New(
+//		di.FailFast(),
+//		di.Provide(NewServer),
+//	)
+func FailFast() Option {
+	return option(func(c *diopts) {
+		c.failFast = true
+	})
+}
+
+// DisallowNil returns container option that makes constructors returning nil pointer, interface,
+// map, slice, function or channel fail with error.
+func DisallowNil() Option {
+	return option(func(c *diopts) {
+		c.disallowNil = true
+	})
+}
+
+// Strict returns container option that enables strict mode. In strict mode nil instances are
+// disallowed and pointers to interfaces, like *io.Writer, are not resolved from interface
+// bindings unless they are provided.
+func Strict() Option {
+	return option(func(c *diopts) {
+		c.strict = true
+	})
+}
+
+// AllowEmptyGroup modifies Resolve() behavior. Group without members is resolved as empty
+// not nil slice instead of ErrTypeNotExists error.
+//
+//	var plugins []Plugin
+//	err := container.Resolve(&plugins, di.AllowEmptyGroup())
+func AllowEmptyGroup() ResolveOption {
+	return resolveOption(func(params *ResolveParams) {
+		params.AllowEmptyGroup = true
+	})
+}
+
+// SkipNilMembers modifies Resolve() behavior. Group members which constructors returned nil
+// are dropped from the resolved slice.
+//
+//	var handlers []Handler
+//	err := container.Resolve(&handlers, di.SkipNilMembers())
+func SkipNilMembers() ResolveOption {
+	return resolveOption(func(params *ResolveParams) {
+		params.SkipNilMembers = true
+	})
+}
+
+// Not modifies Resolve() behavior. Group members that match tags are excluded from the resolved
+// slice. Use "*" as tag value to exclude members with any value of the tag.
+//
+//	var handlers []Handler
+//	err := container.Resolve(&handlers, di.Not(di.Tags{"disabled": "true"}))
+func Not(tags Tags) ResolveOption {
+	return resolveOption(func(params *ResolveParams) {
+		params.Exclude = append(params.Exclude, tags)
+	})
+}
+
+// Weighted modifies Resolve() behavior. One of multiple definitions of the type is selected
+// randomly by di.Weight() on each resolve instead of the multiple definitions error. Instances
+// of the types are cached as usual. Use di.WeightSeed() container option for reproducible
+// selection.
+//
+//	var handler Handler
+//	err := container.Resolve(&handler, di.Weighted())
+func Weighted() ResolveOption {
+	return resolveOption(func(params *ResolveParams) {
+		params.Weighted = true
+	})
+}
+
+// WeightSeed returns container option that seeds random generator of di.Weighted() selection.
+// It makes the selection reproducible in tests.
+func WeightSeed(seed int64) Option {
+	return option(func(c *diopts) {
+		c.weightSeed = &seed
+	})
+}
+
+// DedupeFirst modifies Resolve() behavior. It leaves one group member for each value of
+// key tag, the first provided one. Members without key tag are not deduplicated.
+//
+//	var plugins []Plugin
+//	err := container.Resolve(&plugins, di.DedupeFirst("name"))
+func DedupeFirst(key string) ResolveOption {
+	return resolveOption(func(params *ResolveParams) {
+		params.DedupeKey = key
+		params.DedupeLast = false
+	})
+}
+
+// DedupeLast modifies Resolve() behavior. It leaves one group member for each value of
+// key tag, the last provided one. The member takes position of the first provided one.
+// Members without key tag are not deduplicated.
+//
+//	var plugins []Plugin
+//	err := container.Resolve(&plugins, di.DedupeLast("name"))
+func DedupeLast(key string) ResolveOption {
+	return resolveOption(func(params *ResolveParams) {
+		params.DedupeKey = key
+		params.DedupeLast = true
+	})
+}
+
+// PreferLast returns option that selects the last registered type when multiple definitions
+// match, so a later module can intentionally override an earlier one. Used as container
+// option it applies to all resolves, including constructor dependencies. Used as resolve
+// option it applies only to the resolved type.
+//
+//	container, err := di.New(
+//		di.Provide(NewDefaultLogger),
+//		di.Provide(NewDebugLogger),
+//		di.PreferLast(),
+//	)
+//
+//	var logger Logger
+//	err := container.Resolve(&logger, di.PreferLast())
+func PreferLast() interface {
+	Option
+	ResolveOption
+} {
+	return preferLast{}
+}
+
+type preferLast struct{}
+
+func (preferLast) apply(c *diopts) {
+	c.preferLast = true
+}
+
+func (preferLast) applyResolve(params *ResolveParams) {
+	params.PreferLast = true
+}
+
 // ResolveParams is a resolve parameters.
 type ResolveParams struct {
-	Tags Tags
+	Tags        Tags
+	Undecorated bool
+	PreferLast  bool
+	Groups      []Tags
+	DedupeKey   string
+	DedupeLast  bool
+	// AllowEmptyGroup resolves empty slice instead of ErrTypeNotExists
+	AllowEmptyGroup bool
+	// SkipNilMembers drops nil members from group
+	SkipNilMembers bool
+	// Weighted selects one of multiple definitions randomly by weight
+	Weighted bool
+	// Exclude are tags of group members that are excluded
+	Exclude []Tags
 }
 
 func (p ResolveParams) applyResolve(params *ResolveParams) {
 	*params = p
 }
 
+// resolveParams applies resolve options.
+func resolveParams(options ...ResolveOption) ResolveParams {
+	params := ResolveParams{}
+	for _, opt := range options {
+		opt.applyResolve(&params)
+	}
+	return params
+}
+
 type option func(c *diopts)
 
 func (o option) apply(c *diopts) { o(c) }
@@ -271,6 +847,13 @@ type provideOptions struct {
 	options     []ProvideOption
 }
 
+// struct that contains module struct with options.
+type provideStructOptions struct {
+	frame   callerFrame
+	module  interface{}
+	options []ProvideOption
+}
+
 // struct that contains value with options.
 type provideValueOptions struct {
 	frame   callerFrame
@@ -285,6 +868,12 @@ type invokeOptions struct {
 	options []InvokeOption
 }
 
+// struct that contains deferred provide function.
+type provideLaterOptions struct {
+	frame callerFrame
+	fn    func(c *Container) error
+}
+
 // struct that container resolve target with options.
 type resolveOptions struct {
 	frame   callerFrame
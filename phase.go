@@ -0,0 +1,90 @@
+package di
+
+import (
+	"fmt"
+	"sort"
+)
+
+// phase is a construction phase of node.
+type phase struct {
+	name  string
+	order int
+}
+
+// phased returns nodes with construction phase ordered by phase and registration.
+func (s *defaultSchema) phased() []*node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var nodes []*node
+	for _, list := range s.nodes {
+		for _, n := range list {
+			if n.phase != nil {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		a, b := nodes[i].phase, nodes[j].phase
+		if a.order != b.order {
+			return a.order < b.order
+		}
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return nodes[i].seq < nodes[j].seq
+	})
+	return nodes
+}
+
+// BuildPhased builds types that were provided with di.Phase() option phase by phase in order.
+// Within a phase, types are built in topological order. Dependencies of a type are built with
+// it, even if they belong to a later phase. The failure of a phase stops later phases. Types
+// with phase are not built by Build(), even if they are eager.
+//
+//	container, err := di.New(
+//		di.Provide(NewDatabase, di.Phase("infra", 1)),
+//		di.Provide(NewUserService, di.Phase("services", 2)),
+//		di.Provide(NewServer, di.Phase("http", 3)),
+//	)
+//	if err != nil {
+//		// handle error
+//	}
+//	if err := container.BuildPhased(); err != nil {
+//		// handle error
+//	}
+func (c *Container) BuildPhased() error {
+	if err := c.buildPhased(); err != nil {
+		return errWithStack(err)
+	}
+	return nil
+}
+
+func (c *Container) buildPhased() error {
+	nodes := c.schema.phased()
+	for len(nodes) > 0 {
+		current := nodes[0].phase
+		end := 1
+		for end < len(nodes) && *nodes[end].phase == *current {
+			end++
+		}
+		if err := c.buildPhase(nodes[:end]); err != nil {
+			return fmt.Errorf("phase %s: %w", current.name, err)
+		}
+		nodes = nodes[end:]
+	}
+	return nil
+}
+
+// buildPhase builds nodes of phase in topological order.
+func (c *Container) buildPhase(nodes []*node) error {
+	order, err := c.schema.sort(nodes...)
+	if err != nil {
+		return err
+	}
+	for _, n := range prebuilt(order, nodes...) {
+		if _, err := n.Value(c.schema); err != nil {
+			return fmt.Errorf("%s: %w", n, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package di
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Registry is a read-only view of container that resolves it. It can be injected into any
+// constructor to introspect the container, like in diagnostics endpoint. Unlike *Container,
+// it doesn't resolve types.
+//
+//	func NewDiagnosticsHandler(registry di.Registry) *DiagnosticsHandler {
+//		return &DiagnosticsHandler{registry: registry}
+//	}
+type Registry interface {
+	// Types returns provided types in order of provide.
+	Types() []reflect.Type
+	// Definitions returns definitions of provided types in order of provide.
+	Definitions() []Definition
+	// GroupSize returns count of instances that will be collected into group []t.
+	GroupSize(t reflect.Type, options ...ResolveOption) (int, error)
+}
+
+var registryType = reflect.TypeOf(new(Registry)).Elem()
+
+// registry is a Registry of schema.
+type registry struct {
+	schema *defaultSchema
+}
+
+func (r registry) Types() []reflect.Type {
+	return r.schema.types()
+}
+
+func (r registry) Definitions() []Definition {
+	return r.schema.definitions()
+}
+
+func (r registry) GroupSize(t reflect.Type, options ...ResolveOption) (int, error) {
+	return r.schema.groupSize(t, resolveParams(options...))
+}
+
+// newRegistryNode creates node of schema registry.
+func newRegistryNode(s *defaultSchema) *node {
+	rv := reflect.New(registryType).Elem()
+	rv.Set(reflect.ValueOf(registry{schema: s}))
+	return &node{
+		compiler: valueCompiler{rv: rv},
+		rt:       registryType,
+		tags:     Tags{},
+		rv:       new(reflect.Value),
+		raw:      new(reflect.Value),
+		mu:       new(sync.Mutex),
+	}
+}
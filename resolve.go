@@ -0,0 +1,161 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ResolveImplementations resolves all types that implement element interface of T
+// and returns them as a slice. Unlike groups, implementations don't need to be
+// provided with di.As() or tags, every registered type that implements the interface
+// is collected in the order it was provided.
+//
+//	closers, err := di.ResolveImplementations[[]io.Closer](container)
+//	if err != nil {
+//		// handle error
+//	}
+//	for _, closer := range closers {
+//		closer.Close()
+//	}
+func ResolveImplementations[T any](c *Container, options ...ResolveOption) (T, error) {
+	var result T
+	rt := reflect.TypeOf(&result).Elem()
+	if rt.Kind() != reflect.Slice || rt.Elem().Kind() != reflect.Interface {
+		return result, errWithStack(fmt.Errorf("implementations can be resolved into slice of interfaces, got %s", rt))
+	}
+	params := resolveParams(options...)
+	node, err := c.schema.implementations(rt, params.Tags)
+	if err != nil {
+		return result, errWithStack(err)
+	}
+	if err := c.schema.prepare(node); err != nil {
+		return result, errWithStack(err)
+	}
+	value, err := node.Value(c.schema)
+	if err != nil {
+		return result, errWithStack(fmt.Errorf("%s: %w", node, err))
+	}
+	reflect.ValueOf(&result).Elem().Set(value)
+	return result, nil
+}
+
+// GroupSize returns count of instances that will be collected into group []T with resolve
+// options. The instances are not built, except members of group resolved with
+// di.SkipNilMembers() and slice []T that is provided directly.
+//
+//	size, err := di.GroupSize[Plugin](container)
+//	if err != nil {
+//		// handle error
+//	}
+func GroupSize[T any](c *Container, options ...ResolveOption) (int, error) {
+	size, err := c.groupSize(reflect.TypeOf(new(T)).Elem(), options...)
+	if err != nil {
+		return 0, errWithStack(err)
+	}
+	return size, nil
+}
+
+// ForEach resolves group []T and calls fn for each member in group order. Members are built
+// on the way, so iteration stops on the first error and the rest of members are not built.
+//
+//	err := di.ForEach(container, func(plugin Plugin) error {
+//		return plugin.Init()
+//	})
+func ForEach[T any](c *Container, fn func(T) error, options ...ResolveOption) error {
+	var group []T
+	n, err := c.find(&group, options...)
+	if err != nil {
+		return errWithStack(err)
+	}
+	members := []*node{n}
+	var skipNil bool
+	if compiler, ok := n.compiler.(*groupCompiler); ok {
+		members = compiler.matched
+		skipNil = compiler.skipNil
+	}
+	for _, member := range members {
+		value, err := member.Value(c.schema)
+		if err != nil {
+			return errWithStack(fmt.Errorf("%s: %w", member, err))
+		}
+		if skipNil && isNilMember(value) {
+			continue
+		}
+		if member != n {
+			var item T
+			reflect.ValueOf(&item).Elem().Set(value)
+			if err := fn(item); err != nil {
+				return err
+			}
+			continue
+		}
+		// slice is provided directly
+		reflect.ValueOf(&group).Elem().Set(value)
+		for _, item := range group {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveAs resolves type of from pointer, like new(io.Reader), and returns it as richer
+// interface T that the instance implements. It causes error if the instance doesn't implement T.
+//
+//	closer, err := di.ResolveAs[io.ReadCloser](container, new(io.Reader))
+//	if err != nil {
+//		// handle error
+//	}
+func ResolveAs[T any](c *Container, from Pointer, options ...ResolveOption) (T, error) {
+	var result T
+	rt := reflect.TypeOf(&result).Elem()
+	if rt.Kind() != reflect.Interface {
+		return result, errWithStack(fmt.Errorf("type can be resolved as interface, got %s", rt))
+	}
+	n, err := c.find(from, options...)
+	if err != nil {
+		return result, errWithStack(err)
+	}
+	value, err := n.Value(c.schema)
+	if err != nil {
+		return result, errWithStack(fmt.Errorf("%s: %w", n, err))
+	}
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if !value.IsValid() || !value.Type().Implements(rt) {
+		return result, errWithStack(fmt.Errorf("%s resolved as %s not implement %s", n, concreteType(value), rt))
+	}
+	reflect.ValueOf(&result).Elem().Set(value)
+	return result, nil
+}
+
+// ResolveType resolves type t known at runtime and returns the instance as reflect.Value. It is
+// a low-level primitive to build typed wrappers in generic code:
+//
+//	func MustResolve[T any](c *di.Container, options ...di.ResolveOption) T {
+//		value, err := di.ResolveType(c, reflect.TypeOf(new(T)).Elem(), options...)
+//		if err != nil {
+//			panic(err)
+//		}
+//		return value.Interface().(T)
+//	}
+func ResolveType(c *Container, t reflect.Type, options ...ResolveOption) (reflect.Value, error) {
+	if t == nil {
+		return reflect.Value{}, errWithStack(fmt.Errorf("invalid type, got nil"))
+	}
+	target := reflect.New(t)
+	if err := c.resolve(target.Interface(), options...); err != nil {
+		return reflect.Value{}, errWithStack(err)
+	}
+	return target.Elem(), nil
+}
+
+// concreteType returns type name of value.
+func concreteType(value reflect.Value) string {
+	if !value.IsValid() {
+		return "nil"
+	}
+	return value.Type().String()
+}
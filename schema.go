@@ -1,8 +1,15 @@
 package di
 
 import (
+	"errors"
 	"fmt"
+	"math/rand"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // schema is a dependency injection schema.
@@ -10,96 +17,687 @@ type schema interface {
 	// find finds reflect.Type with matching Tags.
 	find(t reflect.Type, tags Tags) (*node, error)
 	// register cleanup
-	cleanup(cleanup func())
+	cleanup(cleanup *destructor)
+	// record records instance request
+	record(hit bool)
+	// link records that dependent node uses dependency
+	link(dependency, dependent *node)
+	// disallowNil checks that nil instances are disallowed
+	disallowNil() bool
+	// interceptors returns constructor interceptors
+	interceptors() []Interceptor
+	// singletons returns schema that builds singleton node, scopes build them by their base
+	singletons(n *node) schema
+	// scoped returns storage of scoped node instance
+	scoped(n *node) (*instance, error)
+	// building checks that node is under construction by the caller
+	building(n *node) bool
+	// traced returns schema that resolves dependencies of node, tracing schema records node
+	traced(n *node, cached bool) schema
 }
 
+// registrations counts registered nodes. It is used to order nodes across types.
+var registrations uint64
+
 // schema is a dependency injection schema.
 type defaultSchema struct {
-	parents  []*defaultSchema
-	nodes    map[reflect.Type][]*node
-	cleanups []func()
+	parents []*defaultSchema
+	nodes   map[reflect.Type][]*node
+	// injectables caches nodes of di.Inject structs created on find
+	injectables map[reflect.Type]*node
+	cleanups    []*destructor
+	// dependents is a reverse dependency index: instance to nodes that use it
+	dependents map[*reflect.Value]map[*node]bool
+	// mu guards nodes, injectables, cleanups, stats and dependents
+	mu    sync.Mutex
+	stats CacheStats
+	// preferLast selects the last registered node on multiple definitions
+	preferLast bool
+	// noInjectCache disables injectables cache
+	noInjectCache bool
+	// removeOnNil makes nil constructors remove types
+	removeOnNil bool
+	// nilDisallowed disallows nil instances
+	nilDisallowed bool
+	// strict disables resolving of not provided pointers to interfaces
+	strict bool
+	// scope is a node of scope name
+	scope *node
+	// registry is a node of schema registry
+	registry *node
+	// container is a node of scope container, it is nil if schema is not a scope
+	container *node
+	// intercept are constructor interceptors, guarded by mu
+	intercept []Interceptor
+	// lateRegistration allows to register nodes after resolve
+	lateRegistration bool
+	// failFast validates graph on build
+	failFast bool
+	// base is a schema the scope was created from, it is nil if schema is not a scope
+	base *defaultSchema
+	// instances are scoped instances by node instance pointer, guarded by mu
+	instances map[*reflect.Value]*instance
+	// random is a generator of weighted selection, guarded by mu
+	random *rand.Rand
 }
 
-func (s *defaultSchema) cleanup(cleanup func()) {
+func (s *defaultSchema) cleanup(cleanup *destructor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.cleanups = append(s.cleanups, cleanup)
 }
 
+// destructors returns copy of registered cleanups.
+func (s *defaultSchema) destructors() []*destructor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*destructor(nil), s.cleanups...)
+}
+
+func (s *defaultSchema) disallowNil() bool {
+	return s.nilDisallowed
+}
+
+func (s *defaultSchema) building(n *node) bool {
+	return false
+}
+
+func (s *defaultSchema) traced(n *node, cached bool) schema {
+	return s
+}
+
+func (s *defaultSchema) interceptors() []Interceptor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.intercept
+}
+
+// use adds constructor interceptor.
+func (s *defaultSchema) use(interceptor Interceptor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.intercept = append(s.intercept[:len(s.intercept):len(s.intercept)], interceptor)
+}
+
 // newDefaultSchema creates new dependency injection schema.
 func newDefaultSchema() *defaultSchema {
-	return &defaultSchema{
-		nodes: map[reflect.Type][]*node{},
+	s := &defaultSchema{
+		nodes:       map[reflect.Type][]*node{},
+		injectables: map[reflect.Type]*node{},
+		dependents:  map[*reflect.Value]map[*node]bool{},
+		scope:       newScopeNode(rootScope),
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
+	s.registry = newRegistryNode(s)
+	return s
 }
 
 // register registers reflect.Type provide function with optional Tags. Also, its registers
 // type []<type> for group.
 func (s *defaultSchema) register(n *node) {
 	defer tracer.Trace("Register %s", n)
-	if _, ok := s.nodes[n.rt]; !ok {
-		s.nodes[n.rt] = []*node{n}
-		return
-	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(n)
+}
+
+// add adds node to schema, s.mu must be held.
+func (s *defaultSchema) add(n *node) {
+	n.seq = atomic.AddUint64(&registrations, 1)
 	s.nodes[n.rt] = append(s.nodes[n.rt], n)
 }
 
+// identical checks that constructor node of the same function, type and tags is already
+// registered. It causes error if the function was provided with other options. Closures of the
+// same function literal or method can't be told apart by function pointer, so they are
+// identical only if they are provided with the same options.
+func (s *defaultSchema) identical(n *node) (bool, error) {
+	cmp, ok := n.compiler.(*constructorCompiler)
+	if !ok {
+		return false, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cur := range s.nodes[n.rt] {
+		existing, ok := cur.compiler.(*constructorCompiler)
+		if !ok || existing.fn.Pointer() != cmp.fn.Pointer() {
+			continue
+		}
+		if len(cur.tags) != len(n.tags) || !cur.tags.match(n.tags) {
+			continue
+		}
+		if cur.options != n.options && isClosure(cmp.fn) {
+			continue
+		}
+		if cur.options != n.options {
+			return false, fmt.Errorf("%s is already provided by the same constructor with other options", n)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// replace atomically replaces nodes of t and nodes that share instance with them by nodes.
+// Cached instances of replaced nodes and their dependents are cleared.
+func (s *defaultSchema) replace(t reflect.Type, nodes []*node) {
+	s.mu.Lock()
+	replaced := s.nodes[t]
+	s.drop(t, nil)
+	for _, n := range nodes {
+		s.add(n)
+	}
+	s.mu.Unlock()
+	cleared := map[*reflect.Value]bool{}
+	for _, n := range replaced {
+		s.clear(n, cleared)
+	}
+}
+
+// remove removes nodes of t matching tags and nodes that share instance with them, like
+// di.As() aliases.
+func (s *defaultSchema) remove(t reflect.Type, tags Tags) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop(t, tags)
+}
+
+// drop removes nodes of t matching tags and nodes that share instance with them, s.mu must be
+// held.
+func (s *defaultSchema) drop(t reflect.Type, tags Tags) {
+	removed := map[*reflect.Value]bool{}
+	for _, n := range matchTags(s.nodes[t], tags) {
+		removed[n.rv] = true
+	}
+	for rt, nodes := range s.nodes {
+		kept := nodes[:0:0]
+		for _, n := range nodes {
+			if !removed[n.rv] {
+				kept = append(kept, n)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.nodes, rt)
+			continue
+		}
+		s.nodes[rt] = kept
+	}
+}
+
 // used depth-first topological sort algorithm
 func (s *defaultSchema) prepare(n *node) error {
-	var marks = map[*node]int{}
-	if err := visit(s, n, marks); err != nil {
+	if _, err := s.sort(n); err != nil {
 		return err
 	}
 	return nil
 }
 
+// sort returns nodes and all of their dependencies in topological order. The dependencies
+// shared between nodes are presented in order only once.
+func (s *defaultSchema) sort(nodes ...*node) ([]*node, error) {
+	var marks = map[*node]int{}
+	var order []*node
+	for _, n := range nodes {
+		if err := visit(s, n, marks, &order); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// prebuilt returns nodes of topological order that are built ahead of their dependents.
+// Transient nodes are skipped, except roots: each dependent builds its own transient instance.
+func prebuilt(order []*node, roots ...*node) []*node {
+	nodes := make([]*node, 0, len(order))
+	for _, n := range order {
+		if n.lifetime != LifetimeTransient || contains(roots, n) {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// eager returns nodes that must be built on container build in registration order.
+func (s *defaultSchema) eager() []*node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var nodes []*node
+	for _, list := range s.nodes {
+		for _, n := range list {
+			// phased nodes are built by BuildPhased()
+			if n.eager && n.phase == nil {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].seq < nodes[j].seq
+	})
+	return nodes
+}
+
 // find finds provideFunc by its reflect.Type and Tags.
 func (s *defaultSchema) find(t reflect.Type, tags Tags) (*node, error) {
+	return s.lookup(t, tags, s.preferLast)
+}
+
+// lookup finds provideFunc by its reflect.Type and Tags. If preferLast is true, the last
+// registered node is selected on multiple definitions.
+func (s *defaultSchema) lookup(t reflect.Type, tags Tags, preferLast bool) (*node, error) {
+	// scope name is provided by the schema that resolves type
+	if t == scopeNameType {
+		return s.scope, nil
+	}
+	// registry is a view of the schema that resolves type
+	if t == registryType {
+		return s.registry, nil
+	}
+	// scope container shadows containers of parents
+	if t == containerType && s.container != nil {
+		return s.container, nil
+	}
 	nodes, ok := s.list(t)
+	// slice provided directly collides with group of its elements provided with the same
+	// tags, named slice types are intentionally distinct from groups
+	if ok && t.Kind() == reflect.Slice && t.Name() == "" {
+		if members, _ := s.list(t.Elem()); collides(matchTags(nodes, tags), matchTags(members, tags)) {
+			return nil, fmt.Errorf("type %s%s is provided directly and is a group of provided %s, provide either the slice or its elements", t, tags, t.Elem())
+		}
+	}
 	// type found
 	if ok {
 		matched := matchTags(nodes, tags)
+		if len(matched) == 0 && t.Kind() == reflect.Slice && t.Name() == "" {
+			// tagged group of elements is resolved when slice is provided with other tags
+			return s.group(t, tags)
+		}
 		if len(matched) == 0 {
 			return nil, fmt.Errorf("type %s%s %w", t, tags, ErrTypeNotExists)
 		}
+		if len(matched) > 1 && preferLast {
+			return last(matched), nil
+		}
 		if len(matched) > 1 {
 			return nil, fmt.Errorf("multiple definitions of %s%s, maybe you need to use group type: []%s%s", t, tags, t, tags)
 		}
 		return matched[0], nil
 	}
+	// factory function resolves its result type
+	if isFactory(t) {
+		return s.factory(t, tags)
+	}
+	// pointer to interface resolves interface binding
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface && !s.strict {
+		return s.pointer(t, tags)
+	}
 	// if not a group and not have di.Inject
 	if t.Kind() != reflect.Slice && !canInject(t) {
 		return nil, fmt.Errorf("type %s%s %w", t, tags, ErrTypeNotExists)
 	}
 	if canInject(t) {
-		node := &node{
-			compiler: newTypeCompiler(t),
-			rt:       t,
-			rv:       new(reflect.Value),
-		}
-		// save node for future use
-		s.nodes[t] = append(s.nodes[t], node)
-		return node, nil
+		return s.injectable(t), nil
 	}
 	return s.group(t, tags)
 }
 
+// collides checks that any of slices is provided with the same tags as any of group members.
+func collides(slices, members []*node) bool {
+	for _, slice := range slices {
+		for _, member := range members {
+			if len(slice.tags) == len(member.tags) && slice.tags.match(member.tags) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// injectable returns node of di.Inject struct t. The node is cached in injectables, so it
+// doesn't affect registered types, unless cache is disabled.
+func (s *defaultSchema) injectable(t reflect.Type) *node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// node could be saved concurrently
+	if n, ok := s.injectables[t]; ok {
+		return n
+	}
+	n := &node{
+		compiler: newTypeCompiler(t),
+		rt:       t,
+		rv:       new(reflect.Value),
+		mu:       new(sync.Mutex),
+	}
+	if !s.noInjectCache {
+		s.injectables[t] = n
+	}
+	return n
+}
+
+// types returns registered types of schema and its ancestors in registration order.
+func (s *defaultSchema) types() []reflect.Type {
+	first := map[reflect.Type]uint64{}
+	s.walk(func(n *node) {
+		if seq, ok := first[n.rt]; !ok || n.seq < seq {
+			first[n.rt] = n.seq
+		}
+	})
+	types := make([]reflect.Type, 0, len(first))
+	for t := range first {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return first[types[i]] < first[types[j]]
+	})
+	return types
+}
+
+// typeByName finds registered type by its name, like *pkg.DB or *github.com/org/pkg.DB. Only
+// registered types of schema and its ancestors are looked up.
+func (s *defaultSchema) typeByName(name string) (reflect.Type, error) {
+	var found []reflect.Type
+	for _, t := range s.types() {
+		if t.String() == name || qualifiedName(t) == name {
+			found = append(found, t)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("type %s %w", name, ErrTypeNotExists)
+	}
+	if len(found) > 1 {
+		var names []string
+		for _, t := range found {
+			names = append(names, qualifiedName(t))
+		}
+		return nil, fmt.Errorf("type name %s is ambiguous: %s", name, strings.Join(names, ", "))
+	}
+	return found[0], nil
+}
+
+// qualifiedName returns type name with full package path.
+func qualifiedName(t reflect.Type) string {
+	prefix := ""
+	for t.Kind() == reflect.Ptr && t.Name() == "" {
+		prefix += "*"
+		t = t.Elem()
+	}
+	if t.PkgPath() == "" || t.Name() == "" {
+		return prefix + t.String()
+	}
+	return prefix + t.PkgPath() + "." + t.Name()
+}
+
+// weighted selects random node of t matching tags by weight. Types with single definition are
+// looked up as usual.
+func (s *defaultSchema) weighted(t reflect.Type, tags Tags) (*node, error) {
+	nodes, _ := s.list(t)
+	matched := matchTags(nodes, tags)
+	if len(matched) < 2 {
+		return s.lookup(t, tags, false)
+	}
+	total := 0
+	for _, n := range matched {
+		total += n.weight
+	}
+	s.mu.Lock()
+	r := s.random.Intn(total)
+	s.mu.Unlock()
+	for _, n := range matched {
+		if r < n.weight {
+			return n, nil
+		}
+		r -= n.weight
+	}
+	return matched[len(matched)-1], nil
+}
+
+// seed seeds random generator of weighted selection.
+func (s *defaultSchema) seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.random = rand.New(rand.NewSource(seed))
+}
+
+// last returns the last registered node.
+func last(nodes []*node) *node {
+	result := nodes[0]
+	for _, n := range nodes[1:] {
+		if n.seq > result.seq {
+			result = n
+		}
+	}
+	return result
+}
+
 func (s *defaultSchema) group(t reflect.Type, tags Tags) (*node, error) {
 	group, ok := s.list(t.Elem())
 	if !ok {
 		return nil, fmt.Errorf("type %s%s %w", t, tags, ErrTypeNotExists)
 	}
-	matched := matchTags(group, tags)
-	if len(matched) == 0 {
+	compiler := newGroupCompiler(t, matchTags(group, tags))
+	if len(compiler.matched) == 0 {
 		return nil, fmt.Errorf("type %s%s %w", t, tags, ErrTypeNotExists)
 	}
 	node := &node{
-		compiler: newGroupCompiler(t, matched),
+		compiler: compiler,
 		rt:       t,
 		tags:     tags,
 		rv:       new(reflect.Value),
+		mu:       new(sync.Mutex),
 	}
 	return node, nil
 }
 
+// subgroups creates group node of t that concatenates sub-groups matched by params groups
+// tags in the listed order. Members are deduplicated by params dedupe key.
+func (s *defaultSchema) subgroups(t reflect.Type, params ResolveParams) (*node, error) {
+	groups := params.Groups
+	switch {
+	case t.Kind() == reflect.Slice:
+	case len(groups) > 0:
+		return nil, fmt.Errorf("groups can be concatenated into slice, got %s", t)
+	case params.DedupeKey != "":
+		return nil, fmt.Errorf("group can be deduplicated in slice, got %s", t)
+	case params.SkipNilMembers:
+		return nil, fmt.Errorf("nil members can be skipped in slice, got %s", t)
+	default:
+		return nil, fmt.Errorf("members can be excluded from slice, got %s", t)
+	}
+	if len(groups) == 0 {
+		groups = []Tags{{}}
+	}
+	tags := params.Tags
+	list, _ := s.list(t.Elem())
+	list = excludeTags(matchTags(list, tags), params.Exclude)
+	var matched []*node
+	seen := map[*node]bool{}
+	for _, group := range groups {
+		for _, n := range matchTags(list, group) {
+			if !seen[n] {
+				seen[n] = true
+				matched = append(matched, n)
+			}
+		}
+	}
+	compiler := newGroupCompiler(t, matched)
+	compiler.skipNil = params.SkipNilMembers
+	if params.DedupeKey != "" {
+		compiler.matched = dedupe(compiler.matched, params.DedupeKey, params.DedupeLast)
+	}
+	if len(compiler.matched) == 0 {
+		return nil, fmt.Errorf("type %s%s %w", t, tags, ErrTypeNotExists)
+	}
+	return &node{
+		compiler: compiler,
+		rt:       t,
+		tags:     tags,
+		rv:       new(reflect.Value),
+		mu:       new(sync.Mutex),
+	}, nil
+}
+
+// dedupe leaves one node for each value of key tag. The node takes position of the first
+// node with the value. If last is true, it is the last registered node with the value,
+// otherwise the first one. Nodes without key tag are left as is.
+func dedupe(nodes []*node, key string, last bool) []*node {
+	positions := map[string]int{}
+	result := make([]*node, 0, len(nodes))
+	for _, n := range nodes {
+		value, ok := n.tags[key]
+		if !ok {
+			result = append(result, n)
+			continue
+		}
+		if i, ok := positions[value]; ok {
+			if last {
+				result[i] = n
+			}
+			continue
+		}
+		positions[value] = len(result)
+		result = append(result, n)
+	}
+	return result
+}
+
+// factory creates node of factory function t that resolves its result type with tags
+// on each call.
+func (s *defaultSchema) factory(t reflect.Type, tags Tags) (*node, error) {
+	if _, err := s.find(t.Out(0), tags); err != nil {
+		return nil, fmt.Errorf("factory %s%s: %w", t, tags, err)
+	}
+	return &node{
+		compiler: newFactoryCompiler(t, tags),
+		rt:       t,
+		tags:     tags,
+		rv:       new(reflect.Value),
+		mu:       new(sync.Mutex),
+	}, nil
+}
+
+// pick finds node of t with resolve params and prepares it.
+func (s *defaultSchema) pick(t reflect.Type, params ResolveParams) (*node, error) {
+	var node *node
+	var err error
+	if len(params.Groups) > 0 || params.DedupeKey != "" || params.SkipNilMembers || len(params.Exclude) > 0 {
+		node, err = s.subgroups(t, params)
+	} else if params.Weighted {
+		node, err = s.weighted(t, params.Tags)
+	} else {
+		node, err = s.lookup(t, params.Tags, params.PreferLast || s.preferLast)
+	}
+	if errors.Is(err, ErrTypeNotExists) && params.AllowEmptyGroup && t.Kind() == reflect.Slice {
+		node, err = newEmptyGroupNode(t, params.Tags), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.prepare(node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// groupSize returns count of instances that will be collected into group []t with resolve
+// params. Members are not built, except members of group that skips nil members and slice
+// that is provided directly.
+func (s *defaultSchema) groupSize(t reflect.Type, params ResolveParams) (int, error) {
+	params.AllowEmptyGroup = true
+	n, err := s.pick(reflect.SliceOf(t), params)
+	if err != nil {
+		return 0, err
+	}
+	compiler, ok := n.compiler.(*groupCompiler)
+	if !ok {
+		value, err := n.Value(s)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", n, err)
+		}
+		return value.Len(), nil
+	}
+	if !compiler.skipNil {
+		return len(compiler.matched), nil
+	}
+	var size int
+	for _, member := range compiler.matched {
+		value, err := member.Value(s)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", member, err)
+		}
+		if !isNilMember(value) {
+			size++
+		}
+	}
+	return size, nil
+}
+
+// pointer creates node of pointer to interface t.Elem(). The pointer refers to new variable
+// that holds interface binding value. It is created on each find and assigning through it
+// doesn't change the binding.
+func (s *defaultSchema) pointer(t reflect.Type, tags Tags) (*node, error) {
+	binding, err := s.find(t.Elem(), tags)
+	if errors.Is(err, ErrTypeNotExists) {
+		return nil, fmt.Errorf("type %s%s %w, pointer to interface is resolved from %s%s binding", t, tags, ErrTypeNotExists, t.Elem(), tags)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &node{
+		compiler: newPointerCompiler(t, binding),
+		rt:       t,
+		tags:     tags,
+		rv:       new(reflect.Value),
+		mu:       new(sync.Mutex),
+	}, nil
+}
+
+// implementations finds all registered nodes whose type implements interface t and
+// creates group node of them. Nodes that share a value, like di.As() aliases, are
+// collected once in registration order.
+func (s *defaultSchema) implementations(t reflect.Type, tags Tags) (*node, error) {
+	var candidates []*node
+	s.walk(func(n *node) {
+		if n.rt.Implements(t.Elem()) {
+			candidates = append(candidates, n)
+		}
+	})
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].seq < candidates[j].seq
+	})
+	seen := map[*reflect.Value]bool{}
+	var implementations []*node
+	for _, n := range matchTags(candidates, tags) {
+		if seen[n.rv] {
+			continue
+		}
+		seen[n.rv] = true
+		implementations = append(implementations, n)
+	}
+	compiler := newGroupCompiler(t, implementations)
+	if len(compiler.matched) == 0 {
+		return nil, fmt.Errorf("type %s%s %w", t, tags, ErrTypeNotExists)
+	}
+	return &node{
+		compiler: compiler,
+		rt:       t,
+		tags:     tags,
+		rv:       new(reflect.Value),
+		mu:       new(sync.Mutex),
+	}, nil
+}
+
+// walk calls fn for each node of schema and its ancestors.
+func (s *defaultSchema) walk(fn func(n *node)) {
+	for _, parent := range s.parents {
+		parent.walk(fn)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, nodes := range s.nodes {
+		for _, n := range nodes {
+			fn(n)
+		}
+	}
+}
+
 // list lists all the nodes of its reflect.Type
 func (s *defaultSchema) list(t reflect.Type) (nodes []*node, ok bool) {
 	for _, parent := range s.parents {
@@ -108,6 +706,8 @@ func (s *defaultSchema) list(t reflect.Type) (nodes []*node, ok bool) {
 			ok = true
 		}
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if n, o := s.nodes[t]; o {
 		nodes = append(nodes, n...)
 		ok = true
@@ -0,0 +1,155 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ScopeName is a name of the container that resolves type. It can be injected into any
+// constructor to get scope-specific behavior, like per-tenant logging prefix. Containers
+// without a name set by di.Scope() option are named root, scopes created by
+// container.Scope() without it are unnamed.
+//
+//	func NewLogger(scope di.ScopeName) *Logger {
+//		return &Logger{prefix: string(scope)}
+//	}
+type ScopeName string
+
+// rootScope is a default scope name.
+const rootScope ScopeName = "root"
+
+var scopeNameType = reflect.TypeOf(rootScope)
+
+var containerType = reflect.TypeOf(new(Container))
+
+// Scope returns container option that sets container scope name injected as di.ScopeName.
+//
+//	tenant, err := di.New(
+//		di.Scope("tenant-1"),
+//	)
+func Scope(name string) Option {
+	return option(func(c *diopts) {
+		c.scope = ScopeName(name)
+	})
+}
+
+// newScopeNode creates node of scope name.
+func newScopeNode(name ScopeName) *node {
+	return &node{
+		compiler: valueCompiler{rv: reflect.ValueOf(name)},
+		rt:       scopeNameType,
+		tags:     Tags{},
+		rv:       new(reflect.Value),
+		raw:      new(reflect.Value),
+		mu:       new(sync.Mutex),
+	}
+}
+
+// newContainerNode creates node of scope container.
+func newContainerNode(c *Container) *node {
+	return &node{
+		compiler: valueCompiler{rv: reflect.ValueOf(c)},
+		rt:       containerType,
+		tags:     Tags{},
+		rv:       new(reflect.Value),
+		raw:      new(reflect.Value),
+		mu:       new(sync.Mutex),
+	}
+}
+
+// instance is a storage of node instance.
+type instance struct {
+	rv  *reflect.Value
+	raw *reflect.Value
+	mu  *sync.Mutex
+}
+
+// Scope creates child container of scope, like a web request. Types provided with di.Scoped()
+// option are built once per scope, types provided with di.Transient() option are built on each
+// resolve, singletons are built and shared by the container. Options are applied to the scope,
+// so scope-specific values can be provided:
+//
+//	scope, err := container.Scope(di.ProvideValue(request))
+//	if err != nil {
+//		// handle error
+//	}
+//	defer scope.Cleanup()
+//	var tx *sql.Tx
+//	err = scope.Resolve(&tx)
+//
+// Cleanups of scoped and transient instances built by scope are called by scope.Cleanup(),
+// singleton cleanups are called by the container.
+func (c *Container) Scope(options ...Option) (*Container, error) {
+	s := newDefaultSchema()
+	s.parents = []*defaultSchema{c.schema}
+	s.base = c.schema
+	s.instances = map[*reflect.Value]*instance{}
+	s.preferLast = c.schema.preferLast
+	s.noInjectCache = c.schema.noInjectCache
+	s.nilDisallowed = c.schema.nilDisallowed
+	s.strict = c.schema.strict
+	s.intercept = c.schema.interceptors()
+	s.scope = newScopeNode("")
+	scope := &Container{
+		schema:   s,
+		cleanups: []func(){},
+		tokens:   map[string]bool{},
+	}
+	di := diopts{tokens: scope.tokens}
+	for _, opt := range options {
+		opt.apply(&di)
+	}
+	// scope is the container of types resolved by scope, it shadows containers of parents
+	s.container = newContainerNode(scope)
+	if err := scope.apply(di); err != nil {
+		return nil, err
+	}
+	return scope, nil
+}
+
+// singletons returns schema that builds singleton node: the scope or its base the node was
+// registered in. Nodes that are not registered, like groups, are built by the schema.
+func (s *defaultSchema) singletons(n *node) schema {
+	if s.base == nil {
+		return s
+	}
+	cur := s
+	for cur.base != nil {
+		if cur.registered(n) {
+			return cur
+		}
+		cur = cur.base
+	}
+	if nodes, _ := cur.list(n.rt); contains(nodes, n) {
+		return cur
+	}
+	return s
+}
+
+// scoped returns storage of scoped node instance in the scope.
+func (s *defaultSchema) scoped(n *node) (*instance, error) {
+	if s.base == nil {
+		return nil, fmt.Errorf("%s is scoped, resolve it from container.Scope()", n)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.instances[n.rv] == nil {
+		s.instances[n.rv] = &instance{
+			rv:  new(reflect.Value),
+			raw: new(reflect.Value),
+			mu:  new(sync.Mutex),
+		}
+	}
+	return s.instances[n.rv], nil
+}
+
+// contains checks that nodes contain node n.
+func contains(nodes []*node, n *node) bool {
+	for _, cur := range nodes {
+		if cur == n {
+			return true
+		}
+	}
+	return false
+}
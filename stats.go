@@ -0,0 +1,37 @@
+package di
+
+// CacheStats is a statistics of container instance cache.
+type CacheStats struct {
+	// Resolves is a total count of instance requests.
+	Resolves int
+	// Hits is a count of requests that returned already built instance.
+	Hits int
+	// Misses is a count of requests that built new instance.
+	Misses int
+}
+
+// record records instance request.
+func (s *defaultSchema) record(hit bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Resolves++
+	if hit {
+		s.stats.Hits++
+		return
+	}
+	s.stats.Misses++
+}
+
+// resolved checks that any instance was requested.
+func (s *defaultSchema) resolved() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats.Resolves > 0
+}
+
+// cacheStats returns copy of cache statistics.
+func (s *defaultSchema) cacheStats() CacheStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
@@ -95,3 +95,21 @@ func matchTags(nodes []*node, tags Tags) []*node {
 	}
 	return matched
 }
+
+// excludeTags returns nodes that don't match any of exclusions. Empty exclusions are ignored.
+func excludeTags(nodes []*node, exclusions []Tags) []*node {
+	kept := make([]*node, 0, len(nodes))
+	for _, n := range nodes {
+		excluded := false
+		for _, tags := range exclusions {
+			if len(tags) > 0 && n.tags.match(tags) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
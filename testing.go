@@ -0,0 +1,80 @@
+package di
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestContainer is a container for tests. It fails the test on any error instead of
+// returning it.
+type TestContainer struct {
+	*Container
+	tb testing.TB
+}
+
+// NewTest creates container for test in strict mode, see di.Strict(). The test fails if the
+// container can't be created. Cleanup of the container is called on test cleanup.
+//
+//	func TestServer(t *testing.T) {
+//		c := di.NewTest(t,
+//			di.Provide(NewServer),
+//		)
+//		var server *http.Server
+//		c.Resolve(&server)
+//	}
+func NewTest(tb testing.TB, options ...Option) *TestContainer {
+	tb.Helper()
+	c, err := New(append([]Option{Strict()}, options...)...)
+	if err != nil {
+		tb.Fatal(err)
+		return nil
+	}
+	tb.Cleanup(func() {
+		if err := c.Cleanup(); err != nil {
+			tb.Error(err)
+		}
+	})
+	return &TestContainer{
+		Container: c,
+		tb:        tb,
+	}
+}
+
+// Resolve resolves type and fills target pointer. The test fails on error.
+func (c *TestContainer) Resolve(ptr Pointer, options ...ResolveOption) {
+	c.tb.Helper()
+	if err := c.Container.Resolve(ptr, options...); err != nil {
+		c.tb.Fatal(err)
+	}
+}
+
+// Invoke calls the function with dependencies injected. The test fails on error.
+func (c *TestContainer) Invoke(invocation Invocation, options ...InvokeOption) {
+	c.tb.Helper()
+	if err := c.Container.Invoke(invocation, options...); err != nil {
+		c.tb.Fatal(err)
+	}
+}
+
+// AssertOrder checks that types are built to resolve target in the expected order, see
+// container.ResolutionOrder(). The test fails if the order differs.
+//
+//	var app *App
+//	di.AssertOrder(t, container, &app, []reflect.Type{
+//		reflect.TypeOf(new(Database)),
+//		reflect.TypeOf(new(Migrator)),
+//		reflect.TypeOf(new(App)),
+//	})
+func AssertOrder(tb testing.TB, c *Container, target Pointer, expected []reflect.Type, options ...ResolveOption) bool {
+	tb.Helper()
+	order, err := c.ResolutionOrder(target, options...)
+	if err != nil {
+		tb.Errorf("resolution order: %s", err)
+		return false
+	}
+	if !reflect.DeepEqual(order, expected) {
+		tb.Errorf("resolution order mismatch:\nexpected: %v\nactual:   %v", expected, order)
+		return false
+	}
+	return true
+}
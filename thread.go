@@ -0,0 +1,105 @@
+package di
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// threadWorker runs tasks on a goroutine locked to OS thread.
+type threadWorker struct {
+	tasks chan func()
+	once  sync.Once
+	// mu guards stopped and sending of tasks
+	mu      sync.Mutex
+	stopped bool
+}
+
+// newThreadWorker starts worker goroutine locked to OS thread.
+func newThreadWorker() *threadWorker {
+	w := &threadWorker{
+		tasks: make(chan func()),
+	}
+	go w.run()
+	return w
+}
+
+func (w *threadWorker) run() {
+	// the thread terminates with the goroutine because it is not unlocked
+	runtime.LockOSThread()
+	for task := range w.tasks {
+		task()
+	}
+}
+
+// do runs fn on the worker thread and waits for it. Panic of fn is repeated in the caller.
+// Stopped worker does not run fn.
+func (w *threadWorker) do(fn func()) {
+	done := make(chan interface{}, 1)
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return
+	}
+	w.tasks <- func() {
+		defer func() {
+			done <- recover()
+		}()
+		fn()
+	}
+	w.mu.Unlock()
+	if p := <-done; p != nil {
+		panic(p)
+	}
+}
+
+// stop stops the worker, repeated calls do nothing.
+func (w *threadWorker) stop() {
+	w.once.Do(func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.stopped = true
+		close(w.tasks)
+	})
+}
+
+// compileLocked compiles node on the worker thread. If node registers cleanup, the worker is
+// kept to run the cleanup on the same thread.
+func (n *node) compileLocked(dependencies []reflect.Value, s nodeSchema) (reflect.Value, error) {
+	worker := newThreadWorker()
+	ls := &lockedSchema{
+		nodeSchema: s,
+		worker:     worker,
+	}
+	defer func() {
+		if !ls.registered {
+			worker.stop()
+		}
+	}()
+	var rv reflect.Value
+	var err error
+	worker.do(func() {
+		rv, err = n.compile(dependencies, ls)
+	})
+	return rv, err
+}
+
+// lockedSchema is a schema of node compiled on locked thread. It runs cleanups on the thread.
+type lockedSchema struct {
+	nodeSchema
+	worker     *threadWorker
+	registered bool
+}
+
+func (s *lockedSchema) cleanup(cleanup *destructor) {
+	fn := cleanup.fn
+	cleanup.fn = func(r CleanupResolver) (err error) {
+		defer s.worker.stop()
+		s.worker.do(func() {
+			err = fn(r)
+		})
+		return err
+	}
+	s.registered = true
+	s.nodeSchema.cleanup(cleanup)
+}
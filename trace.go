@@ -0,0 +1,90 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Trace is a tree of types traversed by invocation. Each dependency is the node that was
+// actually resolved on build, like tagged type, group members that matched conditions,
+// implementation of interface or type resolved by factory during construction.
+type Trace struct {
+	// Type is a type of node or invocation function of the root.
+	Type reflect.Type
+	// Tags are tags of node.
+	Tags Tags
+	// Cached is true if instance was built before invocation.
+	Cached bool
+	// Dependencies are traces of node dependencies in order of parameters.
+	Dependencies []*Trace
+}
+
+// String returns indented tree of trace.
+//
+//	func(*http.Server)
+//	  *http.Server
+//	    *http.ServeMux[name:public] (cached)
+func (t *Trace) String() string {
+	var b strings.Builder
+	t.write(&b, 0)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (t *Trace) write(b *strings.Builder, depth int) {
+	cached := ""
+	if t.Cached {
+		cached = " (cached)"
+	}
+	fmt.Fprintf(b, "%s%s%s%s\n", strings.Repeat("  ", depth), t.Type, t.Tags, cached)
+	for _, dep := range t.Dependencies {
+		dep.write(b, depth+1)
+	}
+}
+
+// InvokeWithTrace calls the function like Invoke() and returns trace of types traversed by the
+// invocation. The trace is returned on invocation error too, if dependencies were resolved. It
+// is useful to find out why the wrong implementation was chosen:
+//
+//	trace, err := container.InvokeWithTrace(StartServer)
+//	fmt.Println(trace)
+func (c *Container) InvokeWithTrace(invocation Invocation, options ...InvokeOption) (*Trace, error) {
+	trace := &Trace{Type: reflect.TypeOf(invocation)}
+	options = append(options, invokeOption(func(params *InvokeParams) {
+		params.trace = trace
+	}))
+	if err := c.invoke(context.Background(), invocation, options...); err != nil {
+		if len(trace.Dependencies) == 0 {
+			trace = nil
+		}
+		return trace, errWithStack(err)
+	}
+	return trace, nil
+}
+
+// traceSchema is a schema that records nodes resolved through it as dependencies of trace.
+type traceSchema struct {
+	schema
+	trace *Trace
+	// mu guards traces, constructors may resolve factories concurrently
+	mu *sync.Mutex
+}
+
+// newTraceSchema creates schema that records resolved nodes into trace.
+func newTraceSchema(s schema, trace *Trace) traceSchema {
+	return traceSchema{schema: s, trace: trace, mu: new(sync.Mutex)}
+}
+
+func (s traceSchema) traced(n *node, cached bool) schema {
+	trace := &Trace{Type: n.rt, Tags: n.tags, Cached: cached}
+	s.mu.Lock()
+	s.trace.Dependencies = append(s.trace.Dependencies, trace)
+	s.mu.Unlock()
+	return traceSchema{schema: s.schema.traced(n, cached), trace: trace, mu: s.mu}
+}
+
+func (s traceSchema) singletons(n *node) schema {
+	return traceSchema{schema: s.schema.singletons(n), trace: s.trace, mu: s.mu}
+}
@@ -0,0 +1,116 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// validate checks that dependencies of all registered nodes exist and the graph has no cycles.
+// Each node reports only its own missing dependencies, so the error of a missing type is not
+// repeated by all of its dependents. Missing dependencies of scoped and transient nodes are not
+// reported, scopes may provide them.
+func (s *defaultSchema) validate() error {
+	s.mu.Lock()
+	var nodes []*node
+	for _, list := range s.nodes {
+		nodes = append(nodes, list...)
+	}
+	s.mu.Unlock()
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].seq < nodes[j].seq
+	})
+	var errs []error
+	for _, n := range nodes {
+		for _, err := range s.check(n) {
+			if n.lifetime != LifetimeSingleton && errors.Is(err, ErrTypeNotExists) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", n, err))
+		}
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs)
+	}
+	// dependencies exist, only cycles are left, each cycle is reported once
+	cycled := map[*node]bool{}
+	for _, n := range nodes {
+		_, err := s.sort(n)
+		var cycle *cycleError
+		if !errors.As(err, &cycle) {
+			// dependencies missing in container are left to scopes
+			if err != nil && n.lifetime == LifetimeSingleton {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if cycled[cycle.path[0]] {
+			continue
+		}
+		for _, cur := range cycle.path {
+			cycled[cur] = true
+		}
+		errs = append(errs, cycle)
+	}
+	return joinErrors(errs)
+}
+
+// check checks that direct dependencies of node exist. Structs with di.Inject are not
+// registered, so their fields are checked with the node that depends on them.
+func (s *defaultSchema) check(n *node) []error {
+	var errs []error
+	for _, dep := range n.dependencies() {
+		found, err := s.find(dep.rt, dep.tags)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, s.checkInjectable(found)...)
+	}
+	if err := checkUnexportedFields(n.rt); err != nil {
+		return append(errs, err)
+	}
+	fields := n.fields()
+	indexes := make([]int, 0, len(fields))
+	for index := range fields {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		field := fields[index]
+		found, err := s.find(field.rt, field.tags)
+		if err != nil && field.optional {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, s.checkInjectable(found)...)
+	}
+	return errs
+}
+
+// checkInjectable checks dependencies of di.Inject struct that is not registered.
+func (s *defaultSchema) checkInjectable(n *node) []error {
+	if !canInject(n.rt) || s.registered(n) {
+		return nil
+	}
+	var errs []error
+	for _, err := range s.check(n) {
+		errs = append(errs, fmt.Errorf("%s: %w", n, err))
+	}
+	return errs
+}
+
+// registered checks that node is registered in schema.
+func (s *defaultSchema) registered(n *node) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cur := range s.nodes[n.rt] {
+		if cur == n {
+			return true
+		}
+	}
+	return false
+}